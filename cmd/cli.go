@@ -1,13 +1,20 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/BESTSELLER/gcp-nuke/config"
 	"github.com/BESTSELLER/gcp-nuke/gcp"
+	"github.com/BESTSELLER/gcp-nuke/helpers"
+	"github.com/BESTSELLER/gcp-nuke/report"
+	"github.com/BESTSELLER/gcp-nuke/throttle"
 	"github.com/urfave/cli/v2"
 )
 
@@ -16,84 +23,386 @@ func Command() {
 	app := &cli.App{
 		Usage:     "The GCP project cleanup tool with added radiation",
 		Version:   "v0.1.0",
-		UsageText: "e.g. gcp-nuke --project test-nuke-262510 --dryrun",
-		Flags: []cli.Flag{
-			&cli.StringFlag{
-				Name:     "project, p",
-				Usage:    "GCP project id to nuke (required)",
-				Required: true,
-			},
-			&cli.BoolFlag{
-				Name:  "dryrun, d",
-				Usage: "Perform a dryrun instead",
-			},
-			&cli.IntFlag{
-				Name:  "timeout, t",
-				Value: 400,
-				Usage: "Timeout for removal of a single resource in seconds",
-			},
-			&cli.IntFlag{
-				Name:  "polltime, p",
-				Value: 10,
-				Usage: "Time for polling resource deletion status in seconds",
-			},
-			&cli.StringFlag{
-				Name:    "exclusionsconfig, ec",
-				Usage:   "Path to exclusions config file",
-				EnvVars: []string{"EXCLUSIONS_CONFIG"},
-				Aliases: []string{"ec"},
-			},
-			&cli.StringFlag{
-				Name:    "gcpaccesstoken",
-				Usage:   "GCP token for authentication",
-				EnvVars: []string{"GCP_ACCESS_TOKEN"},
-			},
+		UsageText: "e.g. gcp-nuke --project test-nuke-262510 --dryrun\ne.g. gcp-nuke --folder 123456789012 --max-parallel-projects 4 --dryrun\ne.g. gcp-nuke plan --project test-nuke-262510 -o plan.json && gcp-nuke apply plan.json",
+		Flags: append(append(append(append(append(append(selectionFlags(), runFlags()...), reportFlags()...), failureFlags()...), loggingFlags()...), progressFlags()...), &cli.BoolFlag{
+			Name:  "dryrun, d",
+			Usage: "Perform a dryrun instead",
+		}),
+		Before: logToStderr,
+		Commands: []*cli.Command{
+			planCommand(),
+			applyCommand(),
+			resourceTypesCommand(),
 		},
 		Action: func(c *cli.Context) error {
-			if c.String("gcpaccesstoken") == "" {
-				return fmt.Errorf("GCP Access Token not provided")
+			config, err := loadConfig(c)
+			if err != nil {
+				return err
 			}
-			token := config.ConvertStringToTokenSource(c.String("gcpaccesstoken"))
-			// Behaviour to delete all resource in parallel in one project at a time - will be made into loop / concurrenct project nuke if required
-			config := config.Config{
-				Project:  c.String("project"),
-				DryRun:   c.Bool("dryrun"),
-				Timeout:  c.Int("timeout"),
-				PollTime: c.Int("polltime"),
-				Context:  gcp.Ctx,
-				// Zones:    gcp.GetZones(gcp.Ctx, c.String("project")),
-				// Regions:  gcp.GetRegions(gcp.Ctx, c.String("project")),
-				GCPToken: token,
+			config.DryRun = c.Bool("dryrun")
+			if err := setupReport(c, &config); err != nil {
+				return err
 			}
-			gcp.AddZonesToConfig(gcp.Ctx, c.String("project"), config)
-			gcp.AddRegionsToConfig(gcp.Ctx, c.String("project"), config)
 
-			if c.String("exclusionsconfig") != "" {
-				// Read exclusions config file and marshall into Config.Exclusions struct
-
-				b, err := os.ReadFile(c.String("exclusionsconfig"))
-				if err != nil {
-					log.Printf("[Error] Exclusions config file not found at %v", c.String("exclusionsconfig"))
-					return err
-				}
-
-				err = json.Unmarshal(b, &config.Exclusions)
-				if err != nil {
-					log.Printf("[Error] Exclusions config file could not be parsed")
-				}
+			projects, err := collectProjects(c, config)
+			if err != nil {
+				return err
+			}
+			if err := loadFilters(c, &config); err != nil {
+				return err
+			}
 
-				log.Printf("Loaded exclusions config: %+v", config.Exclusions)
+			registry, err := loadRegistry(&config)
+			if err != nil {
+				return err
 			}
 
-			log.Printf("[Info] Timeout %v seconds. Polltime %v seconds. Dry run: %v", config.Timeout, config.PollTime, config.DryRun)
-			gcp.RemoveProject(config)
+			slog.Info("Starting run", "timeout_seconds", config.Timeout, "polltime_seconds", config.PollTime, "dry_run", config.DryRun)
+			slog.Info("Projects to nuke", "projects", projects, "max_parallel_projects", c.Int("max-parallel-projects"))
+			stopProgress, err := startProgress(c, &config)
+			if err != nil {
+				return err
+			}
+			results := gcp.RemoveProjects(registry, config, projects, c.Int("max-parallel-projects"))
+			stopProgress()
+			if err := writeReport(c, config); err != nil {
+				return err
+			}
 
-			return nil
+			return projectsFailed(results, config)
 		},
 	}
 
 	err := app.Run(os.Args)
 	if err != nil {
-		log.Fatalf("app.Run: %s", err)
+		slog.Error("Run failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+// runFlags - flags for authentication, deletion timings and API load
+func runFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.IntFlag{
+			Name:  "timeout, t",
+			Value: 400,
+			Usage: "Timeout for removal of a single resource in seconds",
+		},
+		&cli.IntFlag{
+			Name:  "polltime, p",
+			Value: 10,
+			Usage: "Initial interval for polling resource deletion status in seconds, backs off up to 30 seconds",
+		},
+		&cli.IntFlag{
+			Name:  "max-parallel-projects",
+			Value: 1,
+			Usage: "Maximum number of projects to nuke at once",
+		},
+		&cli.IntFlag{
+			Name:  "max-concurrency",
+			Value: 50,
+			Usage: "Maximum number of resources being deleted at once across all projects, 0 for unlimited",
+		},
+		&cli.StringSliceFlag{
+			Name:  "api-concurrency",
+			Value: cli.NewStringSlice("compute=20", "iam=5"),
+			Usage: "Maximum number of resources of one API being deleted at once, as api=limit, e.g. compute=20,iam=5",
+		},
+		&cli.Float64Flag{
+			Name:  "requests-per-second",
+			Value: 10,
+			Usage: "Maximum rate of API calls across all projects, 0 for unlimited. Rate limited calls are retried with backoff, honouring Retry-After",
+		},
+		&cli.StringFlag{
+			Name:    "gcpaccesstoken",
+			Usage:   "Fixed GCP access token for authentication, it is not refreshed. Application Default Credentials are used when no token or credentials file is given",
+			EnvVars: []string{"GCP_ACCESS_TOKEN"},
+		},
+		&cli.StringFlag{
+			Name:  "credentials-file",
+			Usage: "Service account key, authorized user or Workload Identity Federation config file",
+		},
+		&cli.StringSliceFlag{
+			Name:  "impersonate-service-account",
+			Usage: "Service account to impersonate. A comma separated list is a delegation chain ending with the target",
+		},
+	}
+}
+
+// selectionFlags - flags choosing which projects and resources to nuke
+func selectionFlags() []cli.Flag {
+	return append([]cli.Flag{
+		&cli.StringSliceFlag{
+			Name:  "project, p",
+			Usage: "GCP project id to nuke, can be repeated",
+		},
+		&cli.StringFlag{
+			Name:  "folder",
+			Usage: "Nuke every active project below this folder id, including nested folders",
+		},
+		&cli.StringFlag{
+			Name:  "organization",
+			Usage: "Nuke every active project below this organization id",
+		},
+	}, filterFlags()...)
+}
+
+// filterFlags - flags for the exclusions and the resource types a run may delete, also checked again when a plan is applied
+func filterFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    "exclusionsconfig, ec",
+			Usage:   "Path to exclusions config file",
+			EnvVars: []string{"EXCLUSIONS_CONFIG"},
+			Aliases: []string{"ec"},
+		},
+		&cli.StringSliceFlag{
+			Name:  "include-types",
+			Usage: "Only nuke these resource types and the types they depend on, e.g. ContainerGKEClusters,ComputeDisks. Overrides include_types in the config",
+		},
+		&cli.StringSliceFlag{
+			Name:  "exclude-types",
+			Usage: "Never nuke these resource types, e.g. IAMServiceAccount. Overrides exclude_types in the config",
+		},
+		&cli.DurationFlag{
+			Name:  "older-than",
+			Usage: "Only delete resources created at least this long ago, e.g. 24h. Overrides age.older_than in the config",
+		},
+		&cli.DurationFlag{
+			Name:  "newer-than",
+			Usage: "Only delete resources created at most this long ago, e.g. 2h. Overrides age.newer_than in the config",
+		},
+	}
+}
+
+// reportFlags - flags for the machine readable run report
+func reportFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  "report-format",
+			Value: report.FormatJSON,
+			Usage: "Format of the run report: json, csv or markdown",
+		},
+		&cli.StringFlag{
+			Name:  "report-file",
+			Usage: "Write a report of every resource found, deleted, excluded, skipped or failed to this path, - for stdout",
+		},
+	}
+}
+
+// failureFlags - flags for how a run deals with failed resource types and checks that nothing is left
+func failureFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "verify",
+			Value: true,
+			Usage: fmt.Sprintf("List every project again after deleting and fail it when anything not excluded survived, with exit code %v when nothing else failed. Not done in a dryrun", exitCodeVerificationFailed),
+		},
+		&cli.IntFlag{
+			Name:  "max-passes",
+			Value: 3,
+			Usage: "How often everything is listed and deleted again while resource types fail, stops early once a pass deletes nothing. 0 removes the limit",
+		},
+		&cli.BoolFlag{
+			Name:  "continue-on-error",
+			Usage: fmt.Sprintf("Keep deleting resource types that do not depend on a failed one, then list every failure and exit with code %v", exitCodeFailures),
+		},
+	}
+}
+
+const (
+	// exitCodeFailures - exit code of a run that continued on errors and finished with failures
+	exitCodeFailures = 3
+	// exitCodeVerificationFailed - exit code of a run whose deletions succeeded, but where resources survived or could not be verified
+	exitCodeVerificationFailed = 4
+)
+
+// projectsFailed - prints the project summary, every failure when any project failed, and the verdict of the verification.
+// Returns the error the run exits with
+func projectsFailed(results []gcp.ProjectResult, config config.Config) error {
+	failed := gcp.LogProjectSummary(results, config.DryRun)
+	if failed > 0 {
+		gcp.LogFailureSummary(results)
+	}
+	if config.Verify && !config.DryRun && !gcp.LogVerdict(results) && gcp.OnlyVerificationFailed(results) {
+		return cli.Exit(fmt.Sprintf("verification failed for %v of %v project(s)", failed, len(results)), exitCodeVerificationFailed)
+	}
+	if failed == 0 {
+		return nil
+	}
+	if config.ContinueOnError {
+		return cli.Exit(fmt.Sprintf("%v of %v project(s) failed", failed, len(results)), exitCodeFailures)
+	}
+	return fmt.Errorf("%v of %v project(s) failed", failed, len(results))
+}
+
+// setupReport - starts collecting a report when one was requested
+func setupReport(c *cli.Context, config *config.Config) error {
+	if c.String("report-file") == "" {
+		return nil
+	}
+	if err := report.New().Write(c.String("report-format"), io.Discard); err != nil {
+		return err
+	}
+	config.Report = report.New()
+	return nil
+}
+
+// writeReport - writes the collected report, if any
+func writeReport(c *cli.Context, config config.Config) error {
+	if config.Report == nil {
+		return nil
+	}
+	if err := config.Report.WriteFile(c.String("report-format"), c.String("report-file")); err != nil {
+		return fmt.Errorf("report could not be written: %v", err)
+	}
+	slog.Info("Report written", "path", c.String("report-file"))
+	return nil
+}
+
+// loadConfig - builds the config shared by every project from the run flags, Ctrl+C handling starts here
+func loadConfig(c *cli.Context) (config.Config, error) {
+	drain, abort := helpers.SetupCloseHandler(context.Background())
+	credentials := config.Credentials{
+		AccessToken:        c.String("gcpaccesstoken"),
+		File:               c.String("credentials-file"),
+		ImpersonationChain: c.StringSlice("impersonate-service-account"),
+	}
+	token, err := credentials.TokenSource(abort)
+	if err != nil {
+		return config.Config{}, fmt.Errorf("authentication: %v", err)
+	}
+	apiConcurrency, err := parseAPIConcurrency(c.StringSlice("api-concurrency"))
+	if err != nil {
+		return config.Config{}, err
+	}
+	return config.Config{
+		Timeout:  c.Int("timeout"),
+		PollTime: c.Int("polltime"),
+		Context:  abort,
+		Drain:    drain,
+		GCPToken: token,
+		// Only the commands that delete have the flag
+		ContinueOnError: c.Bool("continue-on-error"),
+		MaxPasses:       c.Int("max-passes"),
+		Verify:          c.Bool("verify"),
+		Limiter:         throttle.New(c.Int("max-concurrency"), apiConcurrency, c.Float64("requests-per-second")),
+	}, nil
+}
+
+// parseAPIConcurrency - reads api=limit pairs, short API names such as compute stand for compute.googleapis.com
+func parseAPIConcurrency(values []string) (map[string]int, error) {
+	limits := map[string]int{}
+	for _, value := range values {
+		api, limit, found := strings.Cut(value, "=")
+		number, err := strconv.Atoi(limit)
+		if !found || api == "" || err != nil {
+			return nil, fmt.Errorf("invalid --api-concurrency %q, expected api=limit, e.g. compute=20", value)
+		}
+		if !strings.Contains(api, ".") {
+			api += ".googleapis.com"
+		}
+		limits[api] = number
+	}
+	return limits, nil
+}
+
+// loadFilters - reads the exclusions config file and applies the filter flags on top of it
+func loadFilters(c *cli.Context, config *config.Config) error {
+	if c.String("exclusionsconfig") != "" {
+		// Read exclusions config file and marshall into Config.Exclusions struct
+
+		b, err := os.ReadFile(c.String("exclusionsconfig"))
+		if err != nil {
+			slog.Error("Exclusions config file not found", "path", c.String("exclusionsconfig"))
+			return err
+		}
+
+		err = json.Unmarshal(b, &config.Exclusions)
+		if err != nil {
+			// Never run with a partially understood config, it could delete protected resources
+			slog.Error("Exclusions config file could not be parsed", "path", c.String("exclusionsconfig"))
+			return err
+		}
+
+		slog.Debug("Loaded exclusions config", "exclusions", fmt.Sprintf("%+v", config.Exclusions))
+	}
+
+	if c.IsSet("include-types") {
+		config.Exclusions.IncludeTypes = c.StringSlice("include-types")
+	}
+	if c.IsSet("exclude-types") {
+		config.Exclusions.ExcludeTypes = c.StringSlice("exclude-types")
+	}
+	if c.IsSet("older-than") {
+		config.Exclusions.Age.OlderThan.Duration = c.Duration("older-than")
+	}
+	if c.IsSet("newer-than") {
+		config.Exclusions.Age.NewerThan.Duration = c.Duration("newer-than")
+	}
+	return nil
+}
+
+// loadRegistry - the shipped resource types, narrowed to the selected ones. Resolves the per type age overrides against every shipped type
+func loadRegistry(config *config.Config) (*gcp.Registry, error) {
+	registry, err := gcp.NewDefaultRegistry()
+	if err != nil {
+		return nil, err
+	}
+	if err := resolveAgeTypes(registry, config); err != nil {
+		return nil, err
+	}
+	if len(config.Exclusions.IncludeTypes) == 0 && len(config.Exclusions.ExcludeTypes) == 0 {
+		return registry, nil
+	}
+	registry, err = registry.Select(config.Exclusions.IncludeTypes, config.Exclusions.ExcludeTypes)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("Resource types selected", "types", registry.Names())
+	return registry, nil
+}
+
+// resolveAgeTypes - checks the per type age overrides against the types of the registry, which may be given by config key
+func resolveAgeTypes(registry *gcp.Registry, config *config.Config) error {
+	aliases := map[string]string{}
+	for _, resourceType := range registry.Types() {
+		aliases[resourceType.ConfigKey] = resourceType.Name
+	}
+	return config.Exclusions.Age.ResolveTypes(aliases)
+}
+
+// collectProjects - merges the explicit project ids with those discovered below a folder or organization
+func collectProjects(c *cli.Context, config config.Config) ([]string, error) {
+	projects := []string{}
+	for _, project := range c.StringSlice("project") {
+		if !helpers.SliceContains(projects, project) {
+			projects = append(projects, project)
+		}
+	}
+
+	parents := []string{}
+	if c.String("folder") != "" {
+		parents = append(parents, gcp.FolderParent(c.String("folder")))
+	}
+	if c.String("organization") != "" {
+		parents = append(parents, gcp.OrganizationParent(c.String("organization")))
+	}
+	for _, parent := range parents {
+		slog.Info("Discovering projects", "parent", parent)
+		discovered, err := gcp.DiscoverProjects(config.Context, config.GCPToken, parent)
+		if err != nil {
+			return nil, err
+		}
+		for _, project := range discovered {
+			if !helpers.SliceContains(projects, project) {
+				projects = append(projects, project)
+			}
+		}
+	}
+
+	if len(projects) == 0 {
+		return nil, fmt.Errorf("no projects to nuke, use --project, --folder or --organization")
 	}
+	return projects, nil
 }
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/urfave/cli/v2"
+)
+
+// loggingFlags - flags for the level and format of the log
+func loggingFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  "log-level",
+			Value: "info",
+			Usage: "Lowest level that is logged: debug, info, warn or error",
+		},
+		&cli.StringFlag{
+			Name:  "log-format",
+			Value: "text",
+			Usage: "Log format: text, or json for one object per line",
+		},
+	}
+}
+
+// logToStderr - sends every log line to stderr, see setupLogging
+func logToStderr(c *cli.Context) error {
+	return setupLogging(c, os.Stderr, slog.LevelInfo)
+}
+
+// setupLogging - sends every log line to out through slog, with the chosen format. Lines below the chosen level, or below defaultLevel when
+// --log-level is not given, are dropped. Lines of the standard log package are logged at info
+func setupLogging(c *cli.Context, out io.Writer, defaultLevel slog.Level) error {
+	level := defaultLevel
+	if value, set := flagValue(c, "log-level"); set {
+		if err := level.UnmarshalText([]byte(value)); err != nil {
+			return fmt.Errorf("invalid --log-level %q, expected debug, info, warn or error", value)
+		}
+	}
+	options := &slog.HandlerOptions{Level: level}
+
+	format, _ := flagValue(c, "log-format")
+	var handler slog.Handler
+	switch format {
+	case "text":
+		handler = slog.NewTextHandler(out, options)
+	case "json":
+		handler = slog.NewJSONHandler(out, options)
+	default:
+		return fmt.Errorf("invalid --log-format %q, expected text or json", format)
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// flagValue - the value of a flag from the innermost command it was set on, so that it may be given before or after the command name.
+// The default value when it was not set anywhere
+func flagValue(c *cli.Context, name string) (string, bool) {
+	for _, context := range c.Lineage() {
+		if context.IsSet(name) {
+			return context.String(name), true
+		}
+	}
+	return c.String(name), false
+}
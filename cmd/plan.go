@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/BESTSELLER/gcp-nuke/config"
+	"github.com/BESTSELLER/gcp-nuke/gcp"
+	"github.com/urfave/cli/v2"
+)
+
+// planCommand - writes the exact set of resources a nuke would delete to a plan file
+func planCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "plan",
+		Usage:     "Record the resources that would be deleted in a plan file",
+		UsageText: "e.g. gcp-nuke plan --project test-nuke-262510 -o plan.json",
+		Before:    logToStderr,
+		Flags: append(append(append(selectionFlags(), runFlags()...), loggingFlags()...), &cli.StringFlag{
+			Name:     "output",
+			Aliases:  []string{"o"},
+			Usage:    "Path to write the plan file to",
+			Required: true,
+		}),
+		Action: func(c *cli.Context) error {
+			config, err := loadConfig(c)
+			if err != nil {
+				return err
+			}
+			projects, err := collectProjects(c, config)
+			if err != nil {
+				return err
+			}
+			if err := loadFilters(c, &config); err != nil {
+				return err
+			}
+
+			registry, err := loadRegistry(&config)
+			if err != nil {
+				return err
+			}
+
+			plan, results := gcp.PlanProjects(registry, config, projects, c.Int("max-parallel-projects"))
+			if failed := gcp.LogProjectSummary(results, true); failed > 0 {
+				return fmt.Errorf("%v of %v project(s) could not be planned, no plan written", failed, len(results))
+			}
+			if err := gcp.WritePlan(c.String("output"), plan); err != nil {
+				return err
+			}
+			slog.Info("Plan written", "path", c.String("output"))
+			return nil
+		},
+	}
+}
+
+// applyCommand - deletes only the resources recorded in a plan file
+func applyCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "apply",
+		Usage:     "Delete exactly the resources recorded in a plan file",
+		UsageText: "e.g. gcp-nuke apply plan.json",
+		ArgsUsage: "<plan file>",
+		Before:    logToStderr,
+		Flags:     append(append(append(append(append(filterFlags(), runFlags()...), reportFlags()...), failureFlags()...), loggingFlags()...), progressFlags()...),
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 {
+				return fmt.Errorf("expected exactly one plan file, got %v", c.NArg())
+			}
+			config, err := loadConfig(c)
+			if err != nil {
+				return err
+			}
+			// Exclusions are checked again, a resource protected since the plan is kept
+			if err := loadFilters(c, &config); err != nil {
+				return err
+			}
+			if err := setupReport(c, &config); err != nil {
+				return err
+			}
+
+			plan, err := gcp.ReadPlan(c.Args().First())
+			if err != nil {
+				return err
+			}
+
+			registry, err := applyRegistry(&config, plan)
+			if err != nil {
+				return err
+			}
+
+			slog.Info("Applying plan", "created_at", plan.CreatedAt, "projects", len(plan.Projects))
+			stopProgress, err := startProgress(c, &config)
+			if err != nil {
+				return err
+			}
+			results := gcp.ApplyPlan(registry, config, plan, c.Int("max-parallel-projects"))
+			stopProgress()
+			if err := writeReport(c, config); err != nil {
+				return err
+			}
+
+			return projectsFailed(results, config)
+		},
+	}
+}
+
+// applyRegistry - the planned resource types that are still selected. Items of any other type were never in scope, so they are not listed
+func applyRegistry(config *config.Config, plan gcp.Plan) (*gcp.Registry, error) {
+	registry, err := gcp.NewDefaultRegistry()
+	if err != nil {
+		return nil, err
+	}
+	if err := resolveAgeTypes(registry, config); err != nil {
+		return nil, err
+	}
+	include := plan.Types()
+	if len(config.Exclusions.IncludeTypes) > 0 {
+		selection, err := registry.Select(config.Exclusions.IncludeTypes, config.Exclusions.ExcludeTypes)
+		if err != nil {
+			return nil, err
+		}
+		include = slices.DeleteFunc(include, func(name string) bool {
+			return !slices.Contains(selection.Names(), name)
+		})
+	}
+	include = slices.DeleteFunc(include, func(name string) bool {
+		return slices.Contains(config.Exclusions.ExcludeTypes, name)
+	})
+	if len(include) == 0 {
+		return nil, fmt.Errorf("none of the planned resource types %v are selected", plan.Types())
+	}
+	return registry.Select(include, config.Exclusions.ExcludeTypes)
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/BESTSELLER/gcp-nuke/config"
+	"github.com/BESTSELLER/gcp-nuke/progress"
+	"github.com/urfave/cli/v2"
+)
+
+// progressInterval - how often the live status view is redrawn
+const progressInterval = 500 * time.Millisecond
+
+// progressFlags - flags for the live status view
+func progressFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "progress",
+			Value: true,
+			Usage: "Show a live status view with one line per resource type when stdout is a terminal. Only warnings and errors are logged above it unless --log-level is given",
+		},
+	}
+}
+
+// startProgress - shows the live status view when stdout is a terminal, otherwise the run only logs. Log lines are printed above the view
+// until the returned stop is called, which leaves the final state on screen and logs to stderr again
+func startProgress(c *cli.Context, config *config.Config) (func(), error) {
+	// A report written to stdout would be mixed into the view
+	if !c.Bool("progress") || c.String("report-file") == "-" || !progress.IsTerminal(os.Stdout) {
+		return func() {}, nil
+	}
+	tracker := progress.New()
+	display := progress.Start(os.Stdout, tracker, progressInterval)
+	if err := setupLogging(c, display, slog.LevelWarn); err != nil {
+		display.Stop()
+		return nil, err
+	}
+	config.Progress = tracker
+	return func() {
+		display.Stop()
+		// The flags were already checked when the view started
+		_ = logToStderr(c)
+	}, nil
+}
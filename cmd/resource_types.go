@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/BESTSELLER/gcp-nuke/gcp"
+	"github.com/urfave/cli/v2"
+)
+
+// resourceTypesCommand - prints every supported resource type with its config key, API and dependencies
+func resourceTypesCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "resource-types",
+		Usage:     "List the supported resource types, their config keys, APIs, dependencies and filters",
+		UsageText: "e.g. gcp-nuke resource-types --output json",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "output",
+				Value: "text",
+				Usage: "Output format: text or json",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			registry, err := gcp.NewDefaultRegistry()
+			if err != nil {
+				return err
+			}
+
+			switch c.String("output") {
+			case "json":
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(registry.Types())
+			case "text":
+				writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+				fmt.Fprintln(writer, "TYPE\tCONFIG KEY\tSERVICE\tLABELS\tAGE\tDEPENDENCIES")
+				for _, resourceType := range registry.Types() {
+					fmt.Fprintf(writer, "%v\t%v\t%v\t%v\t%v\t%v\n", resourceType.Name, resourceType.ConfigKey, resourceType.Service,
+						yesNo(resourceType.Filters.Labels), yesNo(resourceType.Filters.Age), strings.Join(resourceType.Dependencies, ", "))
+				}
+				return writer.Flush()
+			}
+			return fmt.Errorf("unknown output %q, expected text or json", c.String("output"))
+		},
+	}
+}
+
+func yesNo(value bool) string {
+	if value {
+		return "yes"
+	}
+	return "no"
+}
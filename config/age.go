@@ -0,0 +1,114 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Duration - a time.Duration written as a string such as "36h" in the config
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalJSON - reads a duration from its string form
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if raw == "" {
+		d.Duration = 0
+		return nil
+	}
+	duration, err := time.ParseDuration(raw)
+	if err != nil {
+		return err
+	}
+	d.Duration = duration
+	return nil
+}
+
+// MarshalJSON - writes a duration in its string form
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// AgeFilter - limits deletion to resources created within a window
+type AgeFilter struct {
+	// OlderThan - only delete resources at least this old
+	OlderThan Duration `json:"older_than"`
+	// NewerThan - only delete resources at most this old
+	NewerThan Duration `json:"newer_than"`
+}
+
+// AgeFilters - a default age filter with per resource type overrides, keyed by resource type name. ResolveTypes also accepts config keys
+type AgeFilters struct {
+	AgeFilter
+	Types map[string]AgeFilter `json:"types"`
+}
+
+// IsZero - reports whether the filter has no limits
+func (f AgeFilter) IsZero() bool {
+	return f.OlderThan.Duration == 0 && f.NewerThan.Duration == 0
+}
+
+// Allows - reports whether a resource created at the given time may be deleted
+func (f AgeFilter) Allows(created, now time.Time) bool {
+	age := now.Sub(created)
+	if f.OlderThan.Duration > 0 && age < f.OlderThan.Duration {
+		return false
+	}
+	if f.NewerThan.Duration > 0 && age > f.NewerThan.Duration {
+		return false
+	}
+	return true
+}
+
+// ResolveTypes - rekeys overrides written with a config key, e.g. compute_instance, by their resource type name, and rejects unknown keys.
+// An override that is silently ignored would delete resources it was meant to protect. aliases maps each config key to its type name
+func (f *AgeFilters) ResolveTypes(aliases map[string]string) error {
+	names := map[string]bool{}
+	for _, name := range aliases {
+		names[name] = true
+	}
+	resolved := make(map[string]AgeFilter, len(f.Types))
+	unknown := []string{}
+	for key, filter := range f.Types {
+		name := key
+		if alias, found := aliases[key]; found {
+			name = alias
+		}
+		if !names[name] {
+			unknown = append(unknown, key)
+			continue
+		}
+		if _, duplicate := resolved[name]; duplicate {
+			return fmt.Errorf("age override for %v is given more than once", name)
+		}
+		resolved[name] = filter
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown resource types %v in age.types, expected resource type names or config keys", unknown)
+	}
+	f.Types = resolved
+	return nil
+}
+
+// For - the age filter for a resource type, where each limit set on the type overrides the default
+func (f AgeFilters) For(resourceType string) AgeFilter {
+	filter := f.AgeFilter
+	override, exists := f.Types[resourceType]
+	if !exists {
+		return filter
+	}
+	if override.OlderThan.Duration != 0 {
+		filter.OlderThan = override.OlderThan
+	}
+	if override.NewerThan.Duration != 0 {
+		filter.NewerThan = override.NewerThan
+	}
+	return filter
+}
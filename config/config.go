@@ -2,41 +2,99 @@ package config
 
 import (
 	"context"
+	"reflect"
+	"strings"
 
+	"github.com/BESTSELLER/gcp-nuke/progress"
+	"github.com/BESTSELLER/gcp-nuke/report"
+	"github.com/BESTSELLER/gcp-nuke/throttle"
 	"golang.org/x/oauth2"
 )
 
 // Config -
 type Config struct {
-	Project    string
-	Zones      []string
-	Regions    []string
-	Timeout    int
-	PollTime   int
-	Context    context.Context
-	DryRun     bool
+	Project string
+	Zones   []string
+	Regions []string
+	// Services - APIs enabled in the project, nil when they could not be looked up
+	Services []string
+	Timeout  int
+	PollTime int
+	// Context - bound to every API call, cancelled to abandon running deletions
+	Context context.Context
+	// Drain - done once no new deletions should start, running ones are still waited for. Defaults to Context
+	Drain  context.Context
+	DryRun bool
+	// ContinueOnError - keep deleting the resource types that do not depend on a failed one, instead of stopping at the first failure
+	ContinueOnError bool
+	// MaxPasses - how often everything is listed and deleted again while types fail, 0 repeats until a pass deletes nothing
+	MaxPasses int
+	// Verify - list every project again after deleting, and fail it when anything not excluded is left
+	Verify     bool
 	Exclusions Exclusions
 	GCPToken   oauth2.TokenSource
+	// Planning - a plan is recorded or applied, so items are listed with everything a plan compares, even where that takes a lookup per item
+	Planning bool
+	// Planned - when set, only these items may be deleted. Keyed by resource type, then item full name, holding the state recorded in the plan
+	Planned map[string]map[string]string
+	// Report - collects the outcome of every resource, nil when no report was requested
+	Report *report.Report
+	// Limiter - bounds concurrent deletions and API calls across every project, nil when unlimited
+	Limiter *throttle.Limiter
+	// Progress - counts items by state for the live status view, nil when there is none
+	Progress *progress.Tracker
 }
 
 type Exclusions struct {
-	BigQuery                    []string `json:"bigquery"`
-	ComputeDisk                 []string `json:"compute_disk"`
-	ComputeFirewall             []string `json:"compute_firewall"`
-	ComputeInstanceGroupsRegion []string `json:"compute_instance_groups_region"`
-	ComputeInstanceGroupsZone   []string `json:"compute_instance_groups_zone"`
-	ComputeInstanceTemplate     []string `json:"compute_instance_template"`
-	ComputeInstance             []string `json:"compute_instance"`
-	ComputeNetworkPeering       []string `json:"compute_network_peering"`
-	ComputeRegionAutoscaler     []string `json:"compute_region_autoscaler"`
-	ComputeRouter               []string `json:"compute_router"`
-	ComputeSubNetwork           []string `json:"compute_subnetwork"`
-	ComputeVPNGateway           []string `json:"compute_vpn_gateway"`
-	ComputeVPNTunnel            []string `json:"compute_vpn_tunnel"`
-	ComputeZoneAutoscaler       []string `json:"compute_zone_autoscaler"`
-	ContainerGKECluster         []string `json:"container_gke_cluster"`
-	GoogleComputeNetwork        []string `json:"google_compute_network"`
-	IAMServiceAccount           []string `json:"iam_service_account"`
+	BigQuery                    NamePatterns `json:"bigquery"`
+	ComputeDisk                 NamePatterns `json:"compute_disk"`
+	ComputeFirewall             NamePatterns `json:"compute_firewall"`
+	ComputeInstanceGroupsRegion NamePatterns `json:"compute_instance_groups_region"`
+	ComputeInstanceGroupsZone   NamePatterns `json:"compute_instance_groups_zone"`
+	ComputeInstanceTemplate     NamePatterns `json:"compute_instance_template"`
+	ComputeInstance             NamePatterns `json:"compute_instance"`
+	ComputeNetworkPeering       NamePatterns `json:"compute_network_peering"`
+	ComputeRegionAutoscaler     NamePatterns `json:"compute_region_autoscaler"`
+	ComputeRouter               NamePatterns `json:"compute_router"`
+	ComputeSubNetwork           NamePatterns `json:"compute_subnetwork"`
+	ComputeVPNGateway           NamePatterns `json:"compute_vpn_gateway"`
+	ComputeVPNTunnel            NamePatterns `json:"compute_vpn_tunnel"`
+	ComputeZoneAutoscaler       NamePatterns `json:"compute_zone_autoscaler"`
+	ContainerGKECluster         NamePatterns `json:"container_gke_cluster"`
+	GoogleComputeNetwork        NamePatterns `json:"google_compute_network"`
+	IAMServiceAccount           NamePatterns `json:"iam_service_account"`
+	PubSubTopic                 NamePatterns `json:"pubsub_topic"`
+	Labels                      LabelFilters `json:"labels"`
+	Age                         AgeFilters   `json:"age"`
+	// IncludeTypes - only these resource types run, plus their dependencies. Empty means every type
+	IncludeTypes []string `json:"include_types"`
+	// ExcludeTypes - resource types that never run
+	ExcludeTypes []string `json:"exclude_types"`
+}
+
+// ExclusionKeys - the json keys of the name exclusions per resource type
+func ExclusionKeys() []string {
+	keys := []string{}
+	exclusions := reflect.TypeOf(Exclusions{})
+	for i := 0; i < exclusions.NumField(); i++ {
+		field := exclusions.Field(i)
+		if field.Type == reflect.TypeOf(NamePatterns{}) {
+			keys = append(keys, strings.Split(field.Tag.Get("json"), ",")[0])
+		}
+	}
+	return keys
+}
+
+// Patterns - the name exclusions stored under a json key, e.g. compute_instance. Unknown keys have none
+func (e Exclusions) Patterns(key string) NamePatterns {
+	exclusions := reflect.ValueOf(e)
+	for i := 0; i < exclusions.NumField(); i++ {
+		field := exclusions.Type().Field(i)
+		if field.Type == reflect.TypeOf(NamePatterns{}) && strings.Split(field.Tag.Get("json"), ",")[0] == key {
+			return exclusions.Field(i).Interface().(NamePatterns)
+		}
+	}
+	return nil
 }
 
 func ConvertStringToTokenSource(token string) oauth2.TokenSource {
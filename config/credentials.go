@@ -0,0 +1,92 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/impersonate"
+	"google.golang.org/api/option"
+)
+
+// cloudPlatformScope - scope requested for every credential, the individual APIs are authorised by IAM
+const cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// credentialFileTypes - credential files accepted by --credentials-file
+var credentialFileTypes = []google.CredentialsType{
+	google.ServiceAccount,
+	google.ExternalAccount,
+	google.AuthorizedUser,
+	google.ExternalAccountAuthorizedUser,
+}
+
+// Credentials - how to authenticate against GCP. Application Default Credentials are used when neither a token nor a file is set
+type Credentials struct {
+	// AccessToken - a fixed OAuth access token, it is never refreshed and expires after about an hour
+	AccessToken string
+	// File - a service account key, authorized user or Workload Identity Federation config file
+	File string
+	// ImpersonationChain - service accounts to impersonate, the last one is the target and any before it are delegates
+	ImpersonationChain []string
+}
+
+// TokenSource - builds a token source for every service client. Apart from a fixed access token, tokens are refreshed before they expire
+func (c Credentials) TokenSource(ctx context.Context) (oauth2.TokenSource, error) {
+	if c.AccessToken != "" && c.File != "" {
+		return nil, fmt.Errorf("use either an access token or a credentials file, not both")
+	}
+
+	var source oauth2.TokenSource
+	switch {
+	case c.AccessToken != "":
+		slog.Warn("Using a fixed access token, it is not refreshed and runs longer than its lifetime will fail")
+		source = ConvertStringToTokenSource(c.AccessToken)
+	case c.File != "":
+		credentials, err := credentialsFromFile(ctx, c.File)
+		if err != nil {
+			return nil, err
+		}
+		source = credentials.TokenSource
+	default:
+		credentials, err := google.FindDefaultCredentials(ctx, cloudPlatformScope)
+		if err != nil {
+			return nil, fmt.Errorf("application default credentials not found, run gcloud auth application-default login or use --credentials-file: %v", err)
+		}
+		source = credentials.TokenSource
+	}
+
+	if len(c.ImpersonationChain) == 0 {
+		return source, nil
+	}
+	target := c.ImpersonationChain[len(c.ImpersonationChain)-1]
+	slog.Info("Impersonating service account", "target", target)
+	return impersonate.CredentialsTokenSource(ctx, impersonate.CredentialsConfig{
+		TargetPrincipal: target,
+		Delegates:       c.ImpersonationChain[:len(c.ImpersonationChain)-1],
+		Scopes:          []string{cloudPlatformScope},
+	}, option.WithTokenSource(source))
+}
+
+// credentialsFromFile - loads a credentials file, accepting only the types in credentialFileTypes
+func credentialsFromFile(ctx context.Context, path string) (*google.Credentials, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("credentials file could not be read: %v", err)
+	}
+	var file struct {
+		Type google.CredentialsType `json:"type"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("credentials file could not be parsed: %v", err)
+	}
+	for _, allowed := range credentialFileTypes {
+		if file.Type == allowed {
+			return google.CredentialsFromJSONWithType(ctx, data, file.Type, cloudPlatformScope)
+		}
+	}
+	return nil, fmt.Errorf("unsupported credentials file type %q, expected one of %v", file.Type, credentialFileTypes)
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Label selector operators
+const (
+	LabelEquals    = "="
+	LabelNotEquals = "!="
+	LabelExists    = "exists"
+	LabelIn        = "in"
+)
+
+// LabelFilters - label selectors applied to every resource type that supports labels
+type LabelFilters struct {
+	// Include - when set, only resources matching all of these selectors are deleted
+	Include []LabelSelector `json:"include"`
+	// Exclude - resources matching any of these selectors are never deleted
+	Exclude []LabelSelector `json:"exclude"`
+}
+
+// LabelSelector - a single label condition, written as "key=value", "key!=value", "key" or "key in (a,b)"
+type LabelSelector struct {
+	Key      string
+	Operator string
+	Values   []string
+	raw      string
+}
+
+var labelInPattern = regexp.MustCompile(`^([^\s=!]+)\s+in\s+\((.*)\)$`)
+
+// ParseLabelSelector - parses a selector from its string form
+func ParseLabelSelector(input string) (LabelSelector, error) {
+	raw := strings.TrimSpace(input)
+	selector := LabelSelector{raw: raw}
+
+	switch {
+	case raw == "":
+		return selector, fmt.Errorf("empty label selector")
+	case labelInPattern.MatchString(raw):
+		match := labelInPattern.FindStringSubmatch(raw)
+		selector.Key = match[1]
+		selector.Operator = LabelIn
+		for _, value := range strings.Split(match[2], ",") {
+			selector.Values = append(selector.Values, strings.TrimSpace(value))
+		}
+	case strings.Contains(raw, "!="):
+		parts := strings.SplitN(raw, "!=", 2)
+		selector.Key = strings.TrimSpace(parts[0])
+		selector.Operator = LabelNotEquals
+		selector.Values = []string{strings.TrimSpace(parts[1])}
+	case strings.Contains(raw, "="):
+		parts := strings.SplitN(raw, "=", 2)
+		selector.Key = strings.TrimSpace(parts[0])
+		selector.Operator = LabelEquals
+		selector.Values = []string{strings.TrimSpace(parts[1])}
+	default:
+		selector.Key = raw
+		selector.Operator = LabelExists
+	}
+
+	if selector.Key == "" || strings.ContainsAny(selector.Key, " ()") {
+		return selector, fmt.Errorf("invalid label selector %q", input)
+	}
+	return selector, nil
+}
+
+// Matches - reports whether the labels satisfy the selector
+func (s LabelSelector) Matches(labels map[string]string) bool {
+	value, exists := labels[s.Key]
+	switch s.Operator {
+	case LabelEquals:
+		return exists && value == s.Values[0]
+	case LabelNotEquals:
+		return !exists || value != s.Values[0]
+	case LabelExists:
+		return exists
+	case LabelIn:
+		if !exists {
+			return false
+		}
+		for _, candidate := range s.Values {
+			if value == candidate {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// String - the selector as written in the config
+func (s LabelSelector) String() string {
+	return s.raw
+}
+
+// UnmarshalJSON - reads a selector from its string form
+func (s *LabelSelector) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	selector, err := ParseLabelSelector(raw)
+	if err != nil {
+		return err
+	}
+	*s = selector
+	return nil
+}
+
+// MarshalJSON - writes a selector in its string form
+func (s LabelSelector) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.raw)
+}
+
+// Allows - reports whether resources with these labels may be deleted
+func (f LabelFilters) Allows(labels map[string]string) bool {
+	for _, selector := range f.Exclude {
+		if selector.Matches(labels) {
+			return false
+		}
+	}
+	for _, selector := range f.Include {
+		if !selector.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
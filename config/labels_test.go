@@ -0,0 +1,113 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseLabelSelector(t *testing.T) {
+	tests := []struct {
+		input    string
+		key      string
+		operator string
+		values   []string
+	}{
+		{"env=sandbox", "env", LabelEquals, []string{"sandbox"}},
+		{" env = sandbox ", "env", LabelEquals, []string{"sandbox"}},
+		{"env=", "env", LabelEquals, []string{""}},
+		{"env!=prod", "env", LabelNotEquals, []string{"prod"}},
+		{"keep", "keep", LabelExists, nil},
+		{"env in (dev, test)", "env", LabelIn, []string{"dev", "test"}},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			selector, err := ParseLabelSelector(test.input)
+			if err != nil {
+				t.Fatalf("ParseLabelSelector(%q): %v", test.input, err)
+			}
+			if selector.Key != test.key || selector.Operator != test.operator || !reflect.DeepEqual(selector.Values, test.values) {
+				t.Errorf("ParseLabelSelector(%q) = %v %v %q, want %v %v %q", test.input, selector.Key, selector.Operator, selector.Values, test.key, test.operator, test.values)
+			}
+		})
+	}
+}
+
+func TestParseLabelSelectorInvalid(t *testing.T) {
+	for _, input := range []string{"", "   ", "=value", "!=value", "two words", "env in dev"} {
+		t.Run(input, func(t *testing.T) {
+			if _, err := ParseLabelSelector(input); err == nil {
+				t.Errorf("ParseLabelSelector(%q) succeeded, want an error", input)
+			}
+		})
+	}
+}
+
+func TestLabelSelectorMatches(t *testing.T) {
+	labels := map[string]string{"env": "dev", "team": "data"}
+	tests := []struct {
+		selector string
+		want     bool
+	}{
+		{"env=dev", true},
+		{"env=prod", false},
+		{"missing=dev", false},
+		{"env!=prod", true},
+		{"env!=dev", false},
+		{"missing!=dev", true},
+		{"team", true},
+		{"missing", false},
+		{"env in (dev,test)", true},
+		{"env in (prod,test)", false},
+		{"missing in (dev)", false},
+	}
+	for _, test := range tests {
+		t.Run(test.selector, func(t *testing.T) {
+			selector, err := ParseLabelSelector(test.selector)
+			if err != nil {
+				t.Fatalf("ParseLabelSelector(%q): %v", test.selector, err)
+			}
+			if got := selector.Matches(labels); got != test.want {
+				t.Errorf("Matches(%v) = %v, want %v", labels, got, test.want)
+			}
+		})
+	}
+}
+
+func TestLabelFiltersAllows(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		labels map[string]string
+		want   bool
+	}{
+		{"no selectors", `{}`, map[string]string{"env": "prod"}, true},
+		{"no selectors, no labels", `{}`, nil, true},
+		{"excluded", `{"exclude": ["keep"]}`, map[string]string{"keep": "yes"}, false},
+		{"exclude any", `{"exclude": ["keep", "env=prod"]}`, map[string]string{"env": "prod"}, false},
+		{"not excluded", `{"exclude": ["keep"]}`, map[string]string{"env": "dev"}, true},
+		{"included", `{"include": ["env=sandbox"]}`, map[string]string{"env": "sandbox"}, true},
+		{"not included", `{"include": ["env=sandbox"]}`, map[string]string{"env": "prod"}, false},
+		{"include without labels", `{"include": ["env=sandbox"]}`, nil, false},
+		{"include all", `{"include": ["env=sandbox", "team=data"]}`, map[string]string{"env": "sandbox"}, false},
+		{"exclude wins over include", `{"include": ["env=sandbox"], "exclude": ["keep"]}`, map[string]string{"env": "sandbox", "keep": ""}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var filters LabelFilters
+			if err := json.Unmarshal([]byte(test.config), &filters); err != nil {
+				t.Fatalf("Unmarshal(%s): %v", test.config, err)
+			}
+			if got := filters.Allows(test.labels); got != test.want {
+				t.Errorf("Allows(%v) with %s = %v, want %v", test.labels, test.config, got, test.want)
+			}
+		})
+	}
+}
+
+func TestLabelFiltersInvalidJSON(t *testing.T) {
+	var filters LabelFilters
+	if err := json.Unmarshal([]byte(`{"include": ["two words"]}`), &filters); err == nil {
+		t.Errorf("Unmarshal of an invalid selector succeeded, want an error")
+	}
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// NamePatterns - an exclusion list, each entry is an exact name, a glob such as "prod-*" or an anchored regex such as "re:^shared-.*$"
+type NamePatterns []NamePattern
+
+// NamePattern - a single exclusion entry, compiled once when it is parsed
+type NamePattern struct {
+	raw     string
+	matcher *regexp.Regexp
+}
+
+const regexPrefix = "re:"
+
+// ParseNamePatterns - compiles exclusion entries, rejecting invalid regular expressions
+func ParseNamePatterns(patterns ...string) (NamePatterns, error) {
+	parsed := make(NamePatterns, 0, len(patterns))
+	for _, pattern := range patterns {
+		matcher, err := compilePattern(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclusion pattern %q: %v", pattern, err)
+		}
+		parsed = append(parsed, NamePattern{raw: pattern, matcher: matcher})
+	}
+	return parsed, nil
+}
+
+// Matches - reports whether the name matches any of the patterns
+func (p NamePatterns) Matches(name string) bool {
+	for _, pattern := range p {
+		if pattern.matcher.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// UnmarshalJSON - reads the list and rejects invalid regular expressions
+func (p *NamePatterns) UnmarshalJSON(data []byte) error {
+	var patterns []string
+	if err := json.Unmarshal(data, &patterns); err != nil {
+		return err
+	}
+	parsed, err := ParseNamePatterns(patterns...)
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
+
+// String - the entry as written in the config
+func (p NamePattern) String() string {
+	return p.raw
+}
+
+// MarshalJSON - writes the entry as written in the config
+func (p NamePattern) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.raw)
+}
+
+// compilePattern - turns an exclusion entry into a regular expression matching the whole name
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if strings.HasPrefix(pattern, regexPrefix) {
+		expression := strings.TrimPrefix(pattern, regexPrefix)
+		if !strings.HasPrefix(expression, "^") || !strings.HasSuffix(expression, "$") {
+			return nil, fmt.Errorf("regular expressions must be anchored with ^ and $")
+		}
+		return regexp.Compile(expression)
+	}
+
+	// Exact names and globs, where * matches any run of characters and ? a single character
+	var expression strings.Builder
+	expression.WriteString("^")
+	for _, char := range pattern {
+		switch char {
+		case '*':
+			expression.WriteString(".*")
+		case '?':
+			expression.WriteString(".")
+		default:
+			expression.WriteString(regexp.QuoteMeta(string(char)))
+		}
+	}
+	expression.WriteString("$")
+	return regexp.Compile(expression.String())
+}
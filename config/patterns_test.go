@@ -0,0 +1,86 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestNamePatternsMatches(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		input    string
+		want     bool
+	}{
+		{"exact short name", []string{"prod-db"}, "prod-db", true},
+		{"exact does not match a prefix", []string{"prod"}, "prod-db", false},
+		{"exact does not match a longer full name", []string{"prod-db"}, "projects/p/zones/z/instances/prod-db", false},
+		{"exact full name", []string{"projects/p/zones/z/instances/prod-db"}, "projects/p/zones/z/instances/prod-db", true},
+		{"glob star", []string{"prod-*"}, "prod-db", true},
+		{"glob star matches empty", []string{"prod-*"}, "prod-", true},
+		{"glob star is anchored", []string{"prod-*"}, "my-prod-db", false},
+		{"glob star over a full name", []string{"projects/p/zones/*/instances/prod-*"}, "projects/p/zones/z/instances/prod-db", true},
+		{"glob question mark", []string{"db-?"}, "db-1", true},
+		{"glob question mark is a single character", []string{"db-?"}, "db-12", false},
+		{"regex metacharacters in globs are literal", []string{"db.1"}, "dbx1", false},
+		{"regex", []string{"re:^shared-[0-9]+$"}, "shared-12", true},
+		{"regex no match", []string{"re:^shared-[0-9]+$"}, "shared-ab", false},
+		{"any of several", []string{"a", "b*"}, "bee", true},
+		{"none", []string{}, "anything", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			patterns, err := ParseNamePatterns(test.patterns...)
+			if err != nil {
+				t.Fatalf("ParseNamePatterns(%q): %v", test.patterns, err)
+			}
+			if got := patterns.Matches(test.input); got != test.want {
+				t.Errorf("Matches(%q) with %q = %v, want %v", test.input, test.patterns, got, test.want)
+			}
+		})
+	}
+}
+
+func TestNamePatternsInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+	}{
+		{"unanchored start", "re:shared-.*$"},
+		{"unanchored end", "re:^shared-.*"},
+		{"invalid expression", "re:^shared-(.*$"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := ParseNamePatterns(test.pattern); err == nil {
+				t.Errorf("ParseNamePatterns(%q) succeeded, want an error", test.pattern)
+			}
+		})
+	}
+}
+
+func TestNamePatternsJSON(t *testing.T) {
+	var exclusions Exclusions
+	if err := json.Unmarshal([]byte(`{"compute_instance": ["prod-*", "re:^db-[0-9]$"]}`), &exclusions); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	patterns := exclusions.Patterns("compute_instance")
+	if !patterns.Matches("prod-web") || !patterns.Matches("db-1") || patterns.Matches("web") {
+		t.Errorf("Patterns(compute_instance) = %v, matched the wrong names", patterns)
+	}
+	if exclusions.Patterns("unknown_key") != nil {
+		t.Errorf("Patterns(unknown_key) = %v, want none", exclusions.Patterns("unknown_key"))
+	}
+
+	data, err := json.Marshal(patterns)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if string(data) != `["prod-*","re:^db-[0-9]$"]` {
+		t.Errorf("Marshal = %s, want the patterns as written", data)
+	}
+
+	if err := json.Unmarshal([]byte(`{"compute_instance": ["re:db"]}`), &exclusions); err == nil {
+		t.Errorf("Unmarshal of an unanchored regex succeeded, want an error")
+	}
+}
@@ -1,66 +1,31 @@
 package gcp
 
 import (
+	"context"
 	"fmt"
-	"log"
-	"sync"
+	"time"
 
 	bq "cloud.google.com/go/bigquery"
-	"github.com/BESTSELLER/gcp-nuke/config"
-	"github.com/BESTSELLER/gcp-nuke/helpers"
-	"golang.org/x/exp/slices"
-	"golang.org/x/sync/errgroup"
-	"golang.org/x/sync/syncmap"
 	"google.golang.org/api/bigquery/v2"
 	"google.golang.org/api/option"
 )
 
-type BigQueryDataset struct {
-	serviceClient *bigquery.Service
-	base          ResourceBase
-	resourceMap   syncmap.Map
-	DatasetIDs    []string
-}
+type BigQueryDataset struct{}
 
 func (c *BigQueryDataset) Name() string {
 	return "BigQueryDataset"
 }
 
-func (c *BigQueryDataset) ToSlice() (slice []string) {
-	return helpers.SortedSyncMapKeys(&c.resourceMap)
+func (c *BigQueryDataset) Service() string {
+	return "bigquery.googleapis.com"
 }
 
-func (c *BigQueryDataset) Setup(config config.Config) {
-	c.base.config = config
-
-	bigqueryService, err := bigquery.NewService(Ctx, option.WithTokenSource(config.GCPToken))
-	if err != nil {
-		log.Fatalf("BigQueryDataset.Setup.NewService: %s", err)
-	}
-
-	bigqueryResource := BigQueryDataset{
-		serviceClient: bigqueryService,
-	}
-	register(&bigqueryResource)
+func (c *BigQueryDataset) ConfigKey() string {
+	return "bigquery"
 }
 
-func (c *BigQueryDataset) List(refreshCache bool) []string {
-	if !refreshCache {
-		return c.ToSlice()
-	}
-	// Refresh resource map
-	c.resourceMap = sync.Map{}
-
-	datasetList, err := c.serviceClient.Datasets.List(c.base.config.Project).Context(Ctx).Do()
-	if err != nil {
-		log.Fatalf("BigQueryDataset.List: %s", err)
-	}
-
-	for _, dataset := range datasetList.Datasets {
-		c.resourceMap.Store(dataset.Id, dataset.DatasetReference.DatasetId)
-	}
-
-	return c.ToSlice()
+func (c *BigQueryDataset) Filters() FilterSupport {
+	return FilterSupport{Labels: true, Age: true}
 }
 
 // Dependencies - Returns a List of resource names to check for
@@ -68,53 +33,87 @@ func (c *BigQueryDataset) Dependencies() []string {
 	return []string{}
 }
 
-func (c *BigQueryDataset) Remove() error {
-	client, err := bq.NewClient(Ctx, c.base.config.Project)
+// bigQueryClients - the REST client lists and looks up datasets, the BigQuery client deletes them with their contents
+type bigQueryClients struct {
+	service *bigquery.Service
+	client  *bq.Client
+}
+
+func (c *BigQueryDataset) newClient(ctx context.Context, b *ResourceBase) (bigQueryClients, error) {
+	service, err := bigquery.NewService(ctx, option.WithTokenSource(b.config.GCPToken))
 	if err != nil {
-		return fmt.Errorf("bigquery.NewClient: %v", err)
+		return bigQueryClients{}, err
 	}
-	// Removal logic
-	errs, _ := errgroup.WithContext(c.base.config.Context)
-
-	c.resourceMap.Range(func(key, value interface{}) bool {
-		datasetID := value.(string)
+	client, err := bq.NewClient(ctx, b.config.Project, option.WithTokenSource(b.config.GCPToken))
+	if err != nil {
+		return bigQueryClients{}, err
+	}
+	return bigQueryClients{service: service, client: client}, nil
+}
 
-		// Check if a resource is exclued from deletion
-		if slices.Contains(c.base.config.Exclusions.BigQuery, datasetID) {
-			log.Printf("[Info] Excluded resource: %v (%v)", datasetID, c.Name())
-			// This datasetID is excluded from deletion, returning
-			return false
+func (c *BigQueryDataset) listPage(ctx context.Context, b *ResourceBase, clients bigQueryClients, pageToken string) ([]Item, string, error) {
+	page, err := clients.service.Datasets.List(b.config.Project).PageToken(pageToken).Context(ctx).Do()
+	if err != nil {
+		return nil, "", err
+	}
+	items := []Item{}
+	for _, dataset := range page.Datasets {
+		datasetID := dataset.DatasetReference.DatasetId
+		item := Item{
+			Name:     datasetID,
+			FullName: "projects/" + b.config.Project + "/datasets/" + datasetID,
+			Location: dataset.Location,
+			Labels:   dataset.Labels,
 		}
+		details, err := c.details(ctx, b, clients, item)
+		if classifyError(err) == ErrorNotFound {
+			// Deleted since the page was listed
+			continue
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		if details != nil {
+			item.CreatedAt = time.UnixMilli(details.CreationTime)
+			item.Fingerprint = details.Etag
+		}
+		items = append(items, item)
+	}
+	return items, page.NextPageToken, nil
+}
 
-		// Parallel instance deletion
-		errs.Go(func() error {
-			if err := client.Dataset(datasetID).DeleteWithContents(Ctx); err != nil {
-				return fmt.Errorf("delete: %v", err)
-			}
-			deletedTables := false
-
-			seconds := 0
-			for deletedTables {
-
-				log.Printf("[Info] Resource currently being deleted %v [type: %v project: %v ] (%v seconds)", datasetID, c.Name(), c.base.config.Project, seconds)
-				tableList, _ := c.serviceClient.Tables.List(c.base.config.Project, datasetID).Context(Ctx).Do()
-				if tableList == nil {
-					deletedTables = true
-				}
+func (c *BigQueryDataset) deleteOne(ctx context.Context, b *ResourceBase, clients bigQueryClients, item Item) (struct{}, error) {
+	if err := clients.client.Dataset(item.Name).DeleteWithContents(ctx); err != nil {
+		return struct{}{}, fmt.Errorf("delete: %w", err)
+	}
+	return struct{}{}, nil
+}
 
-				seconds += c.base.config.PollTime
-				if seconds > c.base.config.Timeout {
-					return fmt.Errorf("[Error] Resource deletion timed out for %v [type: %v project: %v ] (%v seconds)", datasetID, c.Name(), c.base.config.Project, c.base.config.Timeout)
-				}
-			}
+// waitForOp - the dataset is gone once a lookup reports it as not found
+func (c *BigQueryDataset) waitForOp(ctx context.Context, b *ResourceBase, clients bigQueryClients, item Item, _ struct{}) (int, error) {
+	return b.waitFor(ctx, item, "", func(ctx context.Context) (bool, error) {
+		_, err := clients.service.Datasets.Get(item.Project, item.Name).Context(ctx).Do()
+		if classifyError(err) == ErrorNotFound {
+			return true, nil
+		}
+		return false, err
+	})
+}
 
-			c.resourceMap.Delete(datasetID)
-			log.Printf("[Info] Resource deleted %v [type: %v project: %v] (%v seconds)", datasetID, c.Name(), c.base.config.Project, seconds)
-			return nil
-		})
-		return true
+// details - looks up the creation time and etag of a dataset, which the list call does not return.
+// Only done when an age filter or a plan needs them, nil otherwise. Like every API call it waits for the limiter and is retried while rate limited
+func (c *BigQueryDataset) details(ctx context.Context, b *ResourceBase, clients bigQueryClients, item Item) (*bigquery.Dataset, error) {
+	if b.config.Exclusions.Age.For(c.Name()).IsZero() && !b.config.Planning {
+		return nil, nil
+	}
+	dataset, err := retryRateLimited(ctx, b.config, maxListAttempts, "Looking up "+item.FullName, func() (*bigquery.Dataset, error) {
+		if err := b.config.Limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+		return clients.service.Datasets.Get(b.config.Project, item.Name).Context(ctx).Do()
 	})
-	// Wait for all deletions to complete, and return the first non nil error
-	err = errs.Wait()
-	return err
+	if err != nil {
+		return nil, fmt.Errorf("Datasets.Get %v: %w", item.Name, err)
+	}
+	return dataset, nil
 }
@@ -0,0 +1,21 @@
+package gcp
+
+import (
+	"context"
+
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/option"
+)
+
+// computeDefinition - the client and operation handling shared by the compute resource types
+type computeDefinition struct{}
+
+// newClient - creates the compute client
+func (computeDefinition) newClient(ctx context.Context, b *ResourceBase) (*compute.Service, error) {
+	return compute.NewService(ctx, option.WithTokenSource(b.config.GCPToken))
+}
+
+// waitForOp - waits for the compute operation of a deletion
+func (computeDefinition) waitForOp(ctx context.Context, b *ResourceBase, client *compute.Service, item Item, operation *compute.Operation) (int, error) {
+	return b.waitForComputeOperation(ctx, item, client, operation)
+}
@@ -1,25 +1,14 @@
 package gcp
 
 import (
-	"fmt"
-	"log"
-	"sync"
-	"time"
+	"context"
 
-	"github.com/BESTSELLER/gcp-nuke/config"
-	"github.com/BESTSELLER/gcp-nuke/helpers"
-	"golang.org/x/exp/slices"
-	"golang.org/x/sync/errgroup"
-	"golang.org/x/sync/syncmap"
 	"google.golang.org/api/compute/v1"
-	"google.golang.org/api/option"
 )
 
 // ComputeDisks -
 type ComputeDisks struct {
-	serviceClient *compute.Service
-	base          ResourceBase
-	resourceMap   syncmap.Map
+	computeDefinition
 }
 
 // Name - Name of the resourceLister for ComputeDisks
@@ -27,108 +16,57 @@ func (c *ComputeDisks) Name() string {
 	return "ComputeDisks"
 }
 
-// ToSlice - Name of the resourceLister for ComputeDisks
-func (c *ComputeDisks) ToSlice() (slice []string) {
-	return helpers.SortedSyncMapKeys(&c.resourceMap)
+// Service - API that must be enabled for ComputeDisks
+func (c *ComputeDisks) Service() string {
+	return "compute.googleapis.com"
 }
 
-// Setup - populates the struct
-func (c *ComputeDisks) Setup(config config.Config) {
-	c.base.config = config
+// ConfigKey - key of the ComputeDisks exclusions in the config file
+func (c *ComputeDisks) ConfigKey() string {
+	return "compute_disk"
+}
 
-	computeService, err := compute.NewService(Ctx, option.WithTokenSource(config.GCPToken))
-	if err != nil {
-		log.Fatalf("ComputeDisks.Setup.NewService: %s", err)
-	}
-	computeResource := ComputeDisks{
-		serviceClient: computeService,
-	}
-	register(&computeResource)
+// Filters - filters ComputeDisks items can be selected by
+func (c *ComputeDisks) Filters() FilterSupport {
+	return FilterSupport{Labels: true, Age: true}
 }
 
-// List - Returns a list of all ComputeDisks
-func (c *ComputeDisks) List(refreshCache bool) []string {
-	if !refreshCache {
-		return c.ToSlice()
-	}
-	// Refresh resource map
-	c.resourceMap = sync.Map{}
+// Dependencies - Returns a List of resource names to check for
+func (c *ComputeDisks) Dependencies() []string {
+	return []string{}
+}
 
-	for _, zone := range c.base.config.Zones {
-		instanceListCall := c.serviceClient.Disks.List(c.base.config.Project, zone)
-		instanceList, err := instanceListCall.Do()
-		if err != nil {
-			log.Fatalf("ComputeDisks.List: %s", err)
+// listPage - lists one page of the unattached disks in the configured zones
+func (c *ComputeDisks) listPage(ctx context.Context, b *ResourceBase, client *compute.Service, pageToken string) ([]Item, string, error) {
+	page, err := client.Disks.AggregatedList(b.config.Project).PageToken(pageToken).Context(ctx).Do()
+	if err != nil {
+		return nil, "", err
+	}
+	items := []Item{}
+	for scope, scopedList := range page.Items {
+		zone, inScope := aggregatedScope(scope, "zones", b.config.Zones)
+		if !inScope {
+			continue
 		}
-
-		for _, instance := range instanceList.Items {
+		for _, instance := range scopedList.Disks {
 			// Don't delete any attached to instances - these are removed during instance deletion
 			if len(instance.Users) > 0 {
 				continue
 			}
-			instanceResource := DefaultResourceProperties{
-				zone: zone,
-			}
-			c.resourceMap.Store(instance.Name, instanceResource)
+			items = append(items, Item{
+				Name:        instance.Name,
+				FullName:    relativeName(instance.SelfLink),
+				Location:    zone,
+				Labels:      instance.Labels,
+				CreatedAt:   parseTimestamp(instance.CreationTimestamp),
+				Fingerprint: computeID(instance.Id),
+			})
 		}
 	}
-	return c.ToSlice()
+	return items, page.NextPageToken, nil
 }
 
-// Dependencies - Returns a List of resource names to check for
-func (c *ComputeDisks) Dependencies() []string {
-	return []string{}
-}
-
-// Remove -
-func (c *ComputeDisks) Remove() error {
-	// Removal logic
-	errs, _ := errgroup.WithContext(c.base.config.Context)
-
-	c.resourceMap.Range(func(key, value interface{}) bool {
-		instanceID := key.(string)
-		zone := value.(DefaultResourceProperties).zone
-
-		// Check if a resource is exclued from deletion
-		if slices.Contains(c.base.config.Exclusions.ComputeDisk, instanceID) {
-			log.Printf("[Info] Excluded resource: %v (%v)", instanceID, c.Name())
-			// This instanceID is excluded from deletion, returning
-			return false
-		}
-
-		// Parallel instance deletion
-		errs.Go(func() error {
-			deleteCall := c.serviceClient.Disks.Delete(c.base.config.Project, zone, instanceID)
-			operation, err := deleteCall.Do()
-			if err != nil {
-				return err
-			}
-			var opStatus string
-			seconds := 0
-			for opStatus != "DONE" {
-				log.Printf("[Info] Resource currently being deleted %v [type: %v project: %v zone: %v] (%v seconds)", instanceID, c.Name(), c.base.config.Project, zone, seconds)
-
-				operationCall := c.serviceClient.ZoneOperations.Get(c.base.config.Project, zone, operation.Name)
-				checkOpp, err := operationCall.Do()
-				if err != nil {
-					return err
-				}
-				opStatus = checkOpp.Status
-
-				time.Sleep(time.Duration(c.base.config.PollTime) * time.Second)
-				seconds += c.base.config.PollTime
-				if seconds > c.base.config.Timeout {
-					return fmt.Errorf("[Error] Resource deletion timed out for %v [type: %v project: %v zone: %v] (%v seconds)", instanceID, c.Name(), c.base.config.Project, zone, c.base.config.Timeout)
-				}
-			}
-			c.resourceMap.Delete(instanceID)
-
-			log.Printf("[Info] Resource deleted %v [type: %v project: %v zone: %v] (%v seconds)", instanceID, c.Name(), c.base.config.Project, zone, seconds)
-			return nil
-		})
-		return true
-	})
-	// Wait for all deletions to complete, and return the first non nil error
-	err := errs.Wait()
-	return err
+// deleteOne - starts deleting a disk
+func (c *ComputeDisks) deleteOne(ctx context.Context, b *ResourceBase, client *compute.Service, item Item) (*compute.Operation, error) {
+	return client.Disks.Delete(item.Project, item.Location, item.Name).Context(ctx).Do()
 }
@@ -1,25 +1,14 @@
 package gcp
 
 import (
-	"fmt"
-	"log"
-	"sync"
-	"time"
+	"context"
 
-	"github.com/BESTSELLER/gcp-nuke/config"
-	"github.com/BESTSELLER/gcp-nuke/helpers"
-	"golang.org/x/exp/slices"
-	"golang.org/x/sync/errgroup"
-	"golang.org/x/sync/syncmap"
 	"google.golang.org/api/compute/v1"
-	"google.golang.org/api/option"
 )
 
 // ComputeFirewalls -
 type ComputeFirewalls struct {
-	serviceClient *compute.Service
-	base          ResourceBase
-	resourceMap   syncmap.Map
+	computeDefinition
 }
 
 // Name - Name of the resourceLister for ComputeFirewalls
@@ -27,43 +16,19 @@ func (c *ComputeFirewalls) Name() string {
 	return "ComputeFirewalls"
 }
 
-// ToSlice - Name of the resourceLister for ComputeFirewalls
-func (c *ComputeFirewalls) ToSlice() (slice []string) {
-	return helpers.SortedSyncMapKeys(&c.resourceMap)
+// Service - API that must be enabled for ComputeFirewalls
+func (c *ComputeFirewalls) Service() string {
+	return "compute.googleapis.com"
 }
 
-// Setup - populates the struct
-func (c *ComputeFirewalls) Setup(config config.Config) {
-	c.base.config = config
-
-	computeService, err := compute.NewService(Ctx, option.WithTokenSource(config.GCPToken))
-	if err != nil {
-		log.Fatalf("ComputeFirewalls.Setup.NewService: %s", err)
-	}
-	computeResource := ComputeFirewalls{
-		serviceClient: computeService,
-	}
-	register(&computeResource)
+// ConfigKey - key of the ComputeFirewalls exclusions in the config file
+func (c *ComputeFirewalls) ConfigKey() string {
+	return "compute_firewall"
 }
 
-// List - Returns a list of all ComputeFirewalls
-func (c *ComputeFirewalls) List(refreshCache bool) []string {
-	if !refreshCache {
-		return c.ToSlice()
-	}
-	// Refresh resource map
-	c.resourceMap = sync.Map{}
-
-	firewallListCall := c.serviceClient.Firewalls.List(c.base.config.Project)
-	firewallList, err := firewallListCall.Do()
-	if err != nil {
-		log.Fatalf("ComputeFirewalls.List: %s", err)
-	}
-
-	for _, firewall := range firewallList.Items {
-		c.resourceMap.Store(firewall.Name, nil)
-	}
-	return c.ToSlice()
+// Filters - filters ComputeFirewalls items can be selected by
+func (c *ComputeFirewalls) Filters() FilterSupport {
+	return FilterSupport{Labels: false, Age: true}
 }
 
 // Dependencies - Returns a List of resource names to check for
@@ -74,54 +39,26 @@ func (c *ComputeFirewalls) Dependencies() []string {
 	return []string{a.Name(), b.Name(), cl.Name()}
 }
 
-// Remove -
-func (c *ComputeFirewalls) Remove() error {
-	// Removal logic
-	errs, _ := errgroup.WithContext(c.base.config.Context)
-
-	c.resourceMap.Range(func(key, value interface{}) bool {
-		firewallID := key.(string)
-
-		// Check if resource is excluded
-		if slices.Contains(c.base.config.Exclusions.ComputeFirewall, firewallID) {
-			log.Printf("[Info] Excluded resource: %v (%v)", firewallID, c.Name())
-			// Resource is excluded, skip deleting
-			return false
-		}
-
-		// Parallel firewall deletion
-		errs.Go(func() error {
-			deleteCall := c.serviceClient.Firewalls.Delete(c.base.config.Project, firewallID)
-			operation, err := deleteCall.Do()
-			if err != nil {
-				return err
-			}
-			var opStatus string
-			seconds := 0
-			for opStatus != "DONE" {
-				log.Printf("[Info] Resource currently being deleted %v [type: %v project: %v] (%v seconds)", firewallID, c.Name(), c.base.config.Project, seconds)
-
-				operationCall := c.serviceClient.GlobalOperations.Get(c.base.config.Project, operation.Name)
-				checkOpp, err := operationCall.Do()
-				if err != nil {
-					return err
-				}
-				opStatus = checkOpp.Status
-
-				time.Sleep(time.Duration(c.base.config.PollTime) * time.Second)
-				seconds += c.base.config.PollTime
-				if seconds > c.base.config.Timeout {
-					return fmt.Errorf("[Error] Resource deletion timed out for %v [type: %v project: %v] (%v seconds)", firewallID, c.Name(), c.base.config.Project, c.base.config.Timeout)
-				}
-			}
-			c.resourceMap.Delete(firewallID)
-
-			log.Printf("[Info] Resource deleted %v [type: %v project: %v] (%v seconds)", firewallID, c.Name(), c.base.config.Project, seconds)
-			return nil
+// listPage - lists one page of the firewall rules
+func (c *ComputeFirewalls) listPage(ctx context.Context, b *ResourceBase, client *compute.Service, pageToken string) ([]Item, string, error) {
+	page, err := client.Firewalls.List(b.config.Project).PageToken(pageToken).Context(ctx).Do()
+	if err != nil {
+		return nil, "", err
+	}
+	items := []Item{}
+	for _, firewall := range page.Items {
+		items = append(items, Item{
+			Name:        firewall.Name,
+			FullName:    relativeName(firewall.SelfLink),
+			CreatedAt:   parseTimestamp(firewall.CreationTimestamp),
+			Parents:     relativeNames(firewall.Network),
+			Fingerprint: computeID(firewall.Id),
 		})
-		return true
-	})
-	// Wait for all deletions to complete, and return the first non nil error
-	err := errs.Wait()
-	return err
+	}
+	return items, page.NextPageToken, nil
+}
+
+// deleteOne - starts deleting a firewall rule
+func (c *ComputeFirewalls) deleteOne(ctx context.Context, b *ResourceBase, client *compute.Service, item Item) (*compute.Operation, error) {
+	return client.Firewalls.Delete(item.Project, item.Name).Context(ctx).Do()
 }
@@ -1,25 +1,14 @@
 package gcp
 
 import (
-	"fmt"
-	"log"
-	"sync"
-	"time"
+	"context"
 
-	"github.com/BESTSELLER/gcp-nuke/config"
-	"github.com/BESTSELLER/gcp-nuke/helpers"
-	"golang.org/x/exp/slices"
-	"golang.org/x/sync/errgroup"
-	"golang.org/x/sync/syncmap"
 	"google.golang.org/api/compute/v1"
-	"google.golang.org/api/option"
 )
 
 // ComputeInstanceGroupsRegion -
 type ComputeInstanceGroupsRegion struct {
-	serviceClient *compute.Service
-	base          ResourceBase
-	resourceMap   syncmap.Map
+	computeDefinition
 }
 
 // Name - Name of the resourceLister for ComputeInstanceGroupsRegion
@@ -27,48 +16,19 @@ func (c *ComputeInstanceGroupsRegion) Name() string {
 	return "ComputeInstanceGroupsRegion"
 }
 
-// ToSlice - Name of the resourceLister for ComputeInstanceGroupsRegion
-func (c *ComputeInstanceGroupsRegion) ToSlice() (slice []string) {
-	return helpers.SortedSyncMapKeys(&c.resourceMap)
+// Service - API that must be enabled for ComputeInstanceGroupsRegion
+func (c *ComputeInstanceGroupsRegion) Service() string {
+	return "compute.googleapis.com"
 }
 
-// Setup - populates the struct
-func (c *ComputeInstanceGroupsRegion) Setup(config config.Config) {
-	c.base.config = config
-
-	computeService, err := compute.NewService(Ctx, option.WithTokenSource(config.GCPToken))
-	if err != nil {
-		log.Fatalf("ComputeInstanceGroupsRegion.Setup.NewService: %s", err)
-	}
-	computeResource := ComputeInstanceGroupsRegion{
-		serviceClient: computeService,
-	}
-	register(&computeResource)
+// ConfigKey - key of the ComputeInstanceGroupsRegion exclusions in the config file
+func (c *ComputeInstanceGroupsRegion) ConfigKey() string {
+	return "compute_instance_groups_region"
 }
 
-// List - Returns a list of all ComputeInstanceGroupsRegion
-func (c *ComputeInstanceGroupsRegion) List(refreshCache bool) []string {
-	if !refreshCache {
-		return c.ToSlice()
-	}
-	// Refresh resource map
-	c.resourceMap = sync.Map{}
-
-	for _, region := range c.base.config.Regions {
-		instanceListCall := c.serviceClient.RegionInstanceGroupManagers.List(c.base.config.Project, region)
-		instanceList, err := instanceListCall.Do()
-		if err != nil {
-			log.Fatalf("ComputeInstanceGroupsRegion.List: %s", err)
-		}
-
-		for _, instance := range instanceList.Items {
-			instanceResource := DefaultResourceProperties{
-				region: region,
-			}
-			c.resourceMap.Store(instance.Name, instanceResource)
-		}
-	}
-	return c.ToSlice()
+// Filters - filters ComputeInstanceGroupsRegion items can be selected by
+func (c *ComputeInstanceGroupsRegion) Filters() FilterSupport {
+	return FilterSupport{Labels: false, Age: true}
 }
 
 // Dependencies - Returns a List of resource names to check for
@@ -77,54 +37,32 @@ func (c *ComputeInstanceGroupsRegion) Dependencies() []string {
 	return []string{a.Name()}
 }
 
-// Remove -
-func (c *ComputeInstanceGroupsRegion) Remove() error {
-	// Removal logic
-	errs, _ := errgroup.WithContext(c.base.config.Context)
-
-	c.resourceMap.Range(func(key, value interface{}) bool {
-		instanceID := key.(string)
-		region := value.(DefaultResourceProperties).region
-
-		// Check if a resource is exclued from deletion
-		if slices.Contains(c.base.config.Exclusions.ComputeInstanceGroupsRegion, instanceID) {
-			log.Printf("[Info] Excluded resource: %v (%v)", instanceID, c.Name())
-			// This instanceID is excluded from deletion, returning
-			return false
+// listPage - lists one page of the managed instance groups in the configured regions
+func (c *ComputeInstanceGroupsRegion) listPage(ctx context.Context, b *ResourceBase, client *compute.Service, pageToken string) ([]Item, string, error) {
+	page, err := client.InstanceGroupManagers.AggregatedList(b.config.Project).PageToken(pageToken).Context(ctx).Do()
+	if err != nil {
+		return nil, "", err
+	}
+	items := []Item{}
+	for scope, scopedList := range page.Items {
+		region, inScope := aggregatedScope(scope, "regions", b.config.Regions)
+		if !inScope {
+			continue
 		}
+		for _, instance := range scopedList.InstanceGroupManagers {
+			items = append(items, Item{
+				Name:        instance.Name,
+				FullName:    relativeName(instance.SelfLink),
+				Location:    region,
+				CreatedAt:   parseTimestamp(instance.CreationTimestamp),
+				Fingerprint: computeID(instance.Id),
+			})
+		}
+	}
+	return items, page.NextPageToken, nil
+}
 
-		// Parallel instance deletion
-		errs.Go(func() error {
-			deleteCall := c.serviceClient.RegionInstanceGroupManagers.Delete(c.base.config.Project, region, instanceID)
-			operation, err := deleteCall.Do()
-			if err != nil {
-				return err
-			}
-			var opStatus string
-			seconds := 0
-			for opStatus != "DONE" {
-				log.Printf("[Info] Resource currently being deleted %v [type: %v project: %v region: %v] (%v seconds)", instanceID, c.Name(), c.base.config.Project, region, seconds)
-				operationCall := c.serviceClient.RegionOperations.Get(c.base.config.Project, region, operation.Name)
-				checkOpp, err := operationCall.Do()
-				if err != nil {
-					return err
-				}
-				opStatus = checkOpp.Status
-
-				time.Sleep(time.Duration(c.base.config.PollTime) * time.Second)
-				seconds += c.base.config.PollTime
-				if seconds > c.base.config.Timeout {
-					return fmt.Errorf("[Error] Resource deletion timed out for %v [type: %v project: %v region: %v] (%v seconds)", instanceID, c.Name(), c.base.config.Project, region, c.base.config.Timeout)
-				}
-			}
-			c.resourceMap.Delete(instanceID)
-
-			log.Printf("[Info] Resource deleted %v [type: %v project: %v region: %v] (%v seconds)", instanceID, c.Name(), c.base.config.Project, region, seconds)
-			return nil
-		})
-		return true
-	})
-	// Wait for all deletions to complete, and return the first non nil error
-	err := errs.Wait()
-	return err
+// deleteOne - starts deleting a regional managed instance group
+func (c *ComputeInstanceGroupsRegion) deleteOne(ctx context.Context, b *ResourceBase, client *compute.Service, item Item) (*compute.Operation, error) {
+	return client.RegionInstanceGroupManagers.Delete(item.Project, item.Location, item.Name).Context(ctx).Do()
 }
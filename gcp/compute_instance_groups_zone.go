@@ -1,28 +1,17 @@
 package gcp
 
 import (
-	"fmt"
-	"log"
-	"reflect"
-	"sync"
-	"time"
+	"context"
 
-	"github.com/BESTSELLER/gcp-nuke/config"
 	"github.com/BESTSELLER/gcp-nuke/helpers"
-	"golang.org/x/exp/slices"
-	"golang.org/x/sync/errgroup"
-	"golang.org/x/sync/syncmap"
 	"google.golang.org/api/compute/v1"
-	"google.golang.org/api/option"
 )
 
 // ComputeInstanceGroupsZone -
 type ComputeInstanceGroupsZone struct {
-	serviceClient *compute.Service
-	// Required to skip gke nodepools
+	computeDefinition
+	// gkeInstanceGroups - instance groups of GKE node pools, they are deleted with their cluster
 	gkeInstanceGroups []string
-	base              ResourceBase
-	resourceMap       syncmap.Map
 }
 
 // Name - Name of the resourceLister for ComputeInstanceGroupsZone
@@ -30,118 +19,76 @@ func (c *ComputeInstanceGroupsZone) Name() string {
 	return "ComputeInstanceGroupsZone"
 }
 
-// ToSlice - Name of the resourceLister for ComputeInstanceGroupsZone
-func (c *ComputeInstanceGroupsZone) ToSlice() (slice []string) {
-	return helpers.SortedSyncMapKeys(&c.resourceMap)
+// Service - API that must be enabled for ComputeInstanceGroupsZone
+func (c *ComputeInstanceGroupsZone) Service() string {
+	return "compute.googleapis.com"
 }
 
-// Setup - populates the struct
-func (c *ComputeInstanceGroupsZone) Setup(config config.Config) {
-	c.base.config = config
+// ConfigKey - key of the ComputeInstanceGroupsZone exclusions in the config file
+func (c *ComputeInstanceGroupsZone) ConfigKey() string {
+	return "compute_instance_groups_zone"
+}
+
+// Filters - filters ComputeInstanceGroupsZone items can be selected by
+func (c *ComputeInstanceGroupsZone) Filters() FilterSupport {
+	return FilterSupport{Labels: false, Age: true}
+}
+
+// Dependencies - Returns a List of resource names to check for
+func (c *ComputeInstanceGroupsZone) Dependencies() []string {
+	a := ComputeZoneAutoScalers{}
+	return []string{a.Name()}
+}
 
-	computeService, err := compute.NewService(Ctx, option.WithTokenSource(config.GCPToken))
+// prepareList - looks up the instance groups of GKE node pools, without the container API there are none to protect
+func (c *ComputeInstanceGroupsZone) prepareList(ctx context.Context, b *ResourceBase, client *compute.Service) error {
+	c.gkeInstanceGroups = nil
+	gkeClusters := ContainerGKEClusters{}
+	if !serviceEnabled(b.config, gkeClusters.Service()) {
+		return nil
+	}
+	containerService, err := gkeClusters.newClient(ctx, b)
 	if err != nil {
-		log.Fatalf("ComputeInstanceGroupsZone.Setup.NewClient: %s", err)
+		return err
 	}
-	computeResource := ComputeInstanceGroupsZone{
-		serviceClient: computeService,
+	instanceGroups, err := nodePoolInstanceGroups(ctx, containerService, b.config.Project)
+	if err != nil && classifyError(err) != ErrorServiceDisabled {
+		return err
 	}
-	register(&computeResource)
-
-	// Get the node pool list with some reflection rather than re-instantiating
-	a := ContainerGKEClusters{}
-	gkeResource := resourceMap[a.Name()]
-	gkeInstance := reflect.ValueOf(gkeResource).Elem().Addr().Interface().(*ContainerGKEClusters)
-	gkeInstance.Setup(config)
-	gkeInstance.List(true)
-	c.gkeInstanceGroups = gkeInstance.InstanceGroups
+	c.gkeInstanceGroups = instanceGroups
+	return nil
 }
 
-// List - Returns a list of all ComputeInstanceGroupsZone
-func (c *ComputeInstanceGroupsZone) List(refreshCache bool) []string {
-	if !refreshCache {
-		return c.ToSlice()
+// listPage - lists one page of the managed instance groups in the configured zones, leaving out those of GKE node pools
+func (c *ComputeInstanceGroupsZone) listPage(ctx context.Context, b *ResourceBase, client *compute.Service, pageToken string) ([]Item, string, error) {
+	page, err := client.InstanceGroupManagers.AggregatedList(b.config.Project).PageToken(pageToken).Context(ctx).Do()
+	if err != nil {
+		return nil, "", err
 	}
-	// Refresh resource map
-	c.resourceMap = sync.Map{}
-
-	for _, zone := range c.base.config.Zones {
-		instanceListCall := c.serviceClient.InstanceGroupManagers.List(c.base.config.Project, zone)
-		instanceList, err := instanceListCall.Do()
-		if err != nil {
-			log.Fatalf("ComputeInstanceGroupsZone.List: %s", err)
+	items := []Item{}
+	for scope, scopedList := range page.Items {
+		zone, inScope := aggregatedScope(scope, "zones", b.config.Zones)
+		if !inScope {
+			continue
 		}
-
-		for _, instance := range instanceList.Items {
-
+		for _, instance := range scopedList.InstanceGroupManagers {
 			if helpers.SliceContains(c.gkeInstanceGroups, instance.Name) {
 				continue
 			}
 
-			instanceResource := DefaultResourceProperties{
-				zone: zone,
-			}
-			c.resourceMap.Store(instance.Name, instanceResource)
+			items = append(items, Item{
+				Name:        instance.Name,
+				FullName:    relativeName(instance.SelfLink),
+				Location:    zone,
+				CreatedAt:   parseTimestamp(instance.CreationTimestamp),
+				Fingerprint: computeID(instance.Id),
+			})
 		}
 	}
-	return c.ToSlice()
+	return items, page.NextPageToken, nil
 }
 
-// Dependencies - Returns a List of resource names to check for
-func (c *ComputeInstanceGroupsZone) Dependencies() []string {
-	a := ComputeZoneAutoScalers{}
-	return []string{a.Name()}
-}
-
-// Remove -
-func (c *ComputeInstanceGroupsZone) Remove() error {
-	// Removal logic
-	errs, _ := errgroup.WithContext(c.base.config.Context)
-
-	c.resourceMap.Range(func(key, value interface{}) bool {
-		instanceID := key.(string)
-		zone := value.(DefaultResourceProperties).zone
-
-		// Check if a resource is exclued from deletion
-		if slices.Contains(c.base.config.Exclusions.ComputeInstanceTemplate, instanceID) {
-			log.Printf("[Info] Excluded resource: %v (%v)", instanceID, c.Name())
-			// This instanceID is excluded from deletion, returning
-			return false
-		}
-
-		// Parallel instance deletion
-		errs.Go(func() error {
-			deleteCall := c.serviceClient.InstanceGroupManagers.Delete(c.base.config.Project, zone, instanceID)
-			operation, err := deleteCall.Do()
-			if err != nil {
-				return err
-			}
-			var opStatus string
-			seconds := 0
-			for opStatus != "DONE" {
-				log.Printf("[Info] Resource currently being deleted %v [type: %v project: %v zone: %v] (%v seconds)", instanceID, c.Name(), c.base.config.Project, zone, seconds)
-
-				operationCall := c.serviceClient.ZoneOperations.Get(c.base.config.Project, zone, operation.Name)
-				checkOpp, err := operationCall.Do()
-				if err != nil {
-					return err
-				}
-				opStatus = checkOpp.Status
-
-				time.Sleep(time.Duration(c.base.config.PollTime) * time.Second)
-				seconds += c.base.config.PollTime
-				if seconds > c.base.config.Timeout {
-					return fmt.Errorf("[Error] Resource deletion timed out for %v [type: %v project: %v zone: %v] (%v seconds)", instanceID, c.Name(), c.base.config.Project, zone, c.base.config.Timeout)
-				}
-			}
-			c.resourceMap.Delete(instanceID)
-
-			log.Printf("[Info] Resource deleted %v [type: %v project: %v zone: %v] (%v seconds)", instanceID, c.Name(), c.base.config.Project, zone, seconds)
-			return nil
-		})
-		return true
-	})
-	// Wait for all deletions to complete, and return the first non nil error
-	err := errs.Wait()
-	return err
+// deleteOne - starts deleting a zonal managed instance group
+func (c *ComputeInstanceGroupsZone) deleteOne(ctx context.Context, b *ResourceBase, client *compute.Service, item Item) (*compute.Operation, error) {
+	return client.InstanceGroupManagers.Delete(item.Project, item.Location, item.Name).Context(ctx).Do()
 }
@@ -1,25 +1,14 @@
 package gcp
 
 import (
-	"fmt"
-	"log"
-	"sync"
-	"time"
+	"context"
 
-	"github.com/BESTSELLER/gcp-nuke/config"
-	"github.com/BESTSELLER/gcp-nuke/helpers"
-	"golang.org/x/exp/slices"
-	"golang.org/x/sync/errgroup"
-	"golang.org/x/sync/syncmap"
 	"google.golang.org/api/compute/v1"
-	"google.golang.org/api/option"
 )
 
 // ComputeInstanceTemplates -
 type ComputeInstanceTemplates struct {
-	serviceClient *compute.Service
-	base          ResourceBase
-	resourceMap   syncmap.Map
+	computeDefinition
 }
 
 // Name - Name of the resourceLister for ComputeInstanceTemplates
@@ -27,44 +16,19 @@ func (c *ComputeInstanceTemplates) Name() string {
 	return "ComputeInstanceTemplates"
 }
 
-// ToSlice - Name of the resourceLister for ComputeInstanceTemplates
-func (c *ComputeInstanceTemplates) ToSlice() (slice []string) {
-	return helpers.SortedSyncMapKeys(&c.resourceMap)
+// Service - API that must be enabled for ComputeInstanceTemplates
+func (c *ComputeInstanceTemplates) Service() string {
+	return "compute.googleapis.com"
 }
 
-// Setup - populates the struct
-func (c *ComputeInstanceTemplates) Setup(config config.Config) {
-	c.base.config = config
-
-	computeService, err := compute.NewService(Ctx, option.WithTokenSource(config.GCPToken))
-	if err != nil {
-		log.Fatalf("ComputeInstanceTemplates.Setup.NewService: %s", err)
-	}
-	computeResource := ComputeInstanceTemplates{
-		serviceClient: computeService,
-	}
-	register(&computeResource)
+// ConfigKey - key of the ComputeInstanceTemplates exclusions in the config file
+func (c *ComputeInstanceTemplates) ConfigKey() string {
+	return "compute_instance_template"
 }
 
-// List - Returns a list of all ComputeInstanceTemplates
-func (c *ComputeInstanceTemplates) List(refreshCache bool) []string {
-	if !refreshCache {
-		return c.ToSlice()
-	}
-	// Refresh resource map
-	c.resourceMap = sync.Map{}
-
-	instanceListCall := c.serviceClient.InstanceTemplates.List(c.base.config.Project)
-	instanceList, err := instanceListCall.Do()
-	if err != nil {
-		log.Fatalf("ComputeInstanceTemplates.List: %s", err)
-	}
-
-	for _, instance := range instanceList.Items {
-		instanceResource := DefaultResourceProperties{}
-		c.resourceMap.Store(instance.Name, instanceResource)
-	}
-	return c.ToSlice()
+// Filters - filters ComputeInstanceTemplates items can be selected by
+func (c *ComputeInstanceTemplates) Filters() FilterSupport {
+	return FilterSupport{Labels: true, Age: true}
 }
 
 // Dependencies - Returns a List of resource names to check for
@@ -75,53 +39,30 @@ func (c *ComputeInstanceTemplates) Dependencies() []string {
 	return []string{a.Name(), b.Name(), cl.Name()}
 }
 
-// Remove -
-func (c *ComputeInstanceTemplates) Remove() error {
-	// Removal logic
-	errs, _ := errgroup.WithContext(c.base.config.Context)
-
-	c.resourceMap.Range(func(key, value interface{}) bool {
-		instanceID := key.(string)
-
-		// Check if a resource is exclued from deletion
-		if slices.Contains(c.base.config.Exclusions.ComputeInstanceTemplate, instanceID) {
-			log.Printf("[Info] Excluded resource: %v (%v)", instanceID, c.Name())
-			// This instanceID is excluded from deletion, returning
-			return false
+// listPage - lists one page of the instance templates
+func (c *ComputeInstanceTemplates) listPage(ctx context.Context, b *ResourceBase, client *compute.Service, pageToken string) ([]Item, string, error) {
+	page, err := client.InstanceTemplates.List(b.config.Project).PageToken(pageToken).Context(ctx).Do()
+	if err != nil {
+		return nil, "", err
+	}
+	items := []Item{}
+	for _, instance := range page.Items {
+		labels := map[string]string{}
+		if instance.Properties != nil {
+			labels = instance.Properties.Labels
 		}
-
-		// Parallel instance deletion
-		errs.Go(func() error {
-			deleteCall := c.serviceClient.InstanceTemplates.Delete(c.base.config.Project, instanceID)
-			operation, err := deleteCall.Do()
-			if err != nil {
-				return err
-			}
-			var opStatus string
-			seconds := 0
-			for opStatus != "DONE" {
-				log.Printf("[Info] Resource currently being deleted %v [type: %v project: %v] (%v seconds)", instanceID, c.Name(), c.base.config.Project, seconds)
-				operationCall := c.serviceClient.GlobalOperations.Get(c.base.config.Project, operation.Name)
-				checkOpp, err := operationCall.Do()
-				if err != nil {
-					return err
-				}
-				opStatus = checkOpp.Status
-
-				time.Sleep(time.Duration(c.base.config.PollTime) * time.Second)
-				seconds += c.base.config.PollTime
-				if seconds > c.base.config.Timeout {
-					return fmt.Errorf("[Error] Resource deletion timed out for %v [type: %v project: %v] (%v seconds)", instanceID, c.Name(), c.base.config.Project, c.base.config.Timeout)
-				}
-			}
-			c.resourceMap.Delete(instanceID)
-
-			log.Printf("[Info] Resource deleted %v [type: %v project: %v] (%v seconds)", instanceID, c.Name(), c.base.config.Project, seconds)
-			return nil
+		items = append(items, Item{
+			Name:        instance.Name,
+			FullName:    relativeName(instance.SelfLink),
+			Labels:      labels,
+			CreatedAt:   parseTimestamp(instance.CreationTimestamp),
+			Fingerprint: computeID(instance.Id),
 		})
-		return true
-	})
-	// Wait for all deletions to complete, and return the first non nil error
-	err := errs.Wait()
-	return err
+	}
+	return items, page.NextPageToken, nil
+}
+
+// deleteOne - starts deleting an instance template
+func (c *ComputeInstanceTemplates) deleteOne(ctx context.Context, b *ResourceBase, client *compute.Service, item Item) (*compute.Operation, error) {
+	return client.InstanceTemplates.Delete(item.Project, item.Name).Context(ctx).Do()
 }
@@ -1,26 +1,15 @@
 package gcp
 
 import (
-	"fmt"
-	"log"
+	"context"
 	"strings"
-	"sync"
-	"time"
 
-	"github.com/BESTSELLER/gcp-nuke/config"
-	"github.com/BESTSELLER/gcp-nuke/helpers"
-	"golang.org/x/exp/slices"
-	"golang.org/x/sync/errgroup"
-	"golang.org/x/sync/syncmap"
 	"google.golang.org/api/compute/v1"
-	"google.golang.org/api/option"
 )
 
 // ComputeInstances -
 type ComputeInstances struct {
-	serviceClient *compute.Service
-	base          ResourceBase
-	resourceMap   syncmap.Map
+	computeDefinition
 }
 
 // Name - Name of the resourceLister for ComputeInstances
@@ -28,41 +17,39 @@ func (c *ComputeInstances) Name() string {
 	return "ComputeInstances"
 }
 
-// ToSlice - Name of the resourceLister for ComputeInstances
-func (c *ComputeInstances) ToSlice() (slice []string) {
-	return helpers.SortedSyncMapKeys(&c.resourceMap)
+// Service - API that must be enabled for ComputeInstances
+func (c *ComputeInstances) Service() string {
+	return "compute.googleapis.com"
 }
 
-// Setup - populates the struct
-func (c *ComputeInstances) Setup(config config.Config) {
-	c.base.config = config
+// ConfigKey - key of the ComputeInstances exclusions in the config file
+func (c *ComputeInstances) ConfigKey() string {
+	return "compute_instance"
+}
 
-	computeService, err := compute.NewService(Ctx, option.WithTokenSource(config.GCPToken))
-	if err != nil {
-		log.Fatalf("ComputeInstances.Setup.NewService: %s", err)
-	}
-	computeResource := ComputeInstances{
-		serviceClient: computeService,
-	}
-	register(&computeResource)
+// Filters - filters ComputeInstances items can be selected by
+func (c *ComputeInstances) Filters() FilterSupport {
+	return FilterSupport{Labels: true, Age: true}
 }
 
-// List - Returns a list of all ComputeInstances
-func (c *ComputeInstances) List(refreshCache bool) []string {
-	if !refreshCache {
-		return c.ToSlice()
-	}
-	// Refresh resource map
-	c.resourceMap = sync.Map{}
+// Dependencies - Returns a List of resource names to check for
+func (c *ComputeInstances) Dependencies() []string {
+	return []string{}
+}
 
-	for _, zone := range c.base.config.Zones {
-		instanceListCall := c.serviceClient.Instances.List(c.base.config.Project, zone)
-		instanceList, err := instanceListCall.Do()
-		if err != nil {
-			log.Fatalf("ComputeInstances.List: %s", err)
+// listPage - lists one page of the instances in the configured zones that are not managed by an instance group
+func (c *ComputeInstances) listPage(ctx context.Context, b *ResourceBase, client *compute.Service, pageToken string) ([]Item, string, error) {
+	page, err := client.Instances.AggregatedList(b.config.Project).PageToken(pageToken).Context(ctx).Do()
+	if err != nil {
+		return nil, "", err
+	}
+	items := []Item{}
+	for scope, scopedList := range page.Items {
+		zone, inScope := aggregatedScope(scope, "zones", b.config.Zones)
+		if !inScope {
+			continue
 		}
-
-		for _, instance := range instanceList.Items {
+		for _, instance := range scopedList.Instances {
 			skipInstance := false
 			// Skip any managed by instance groups
 			for _, item := range instance.Metadata.Items {
@@ -74,84 +61,34 @@ func (c *ComputeInstances) List(refreshCache bool) []string {
 				continue
 			}
 
-			instanceResource := DefaultResourceProperties{
-				zone: zone,
-			}
-			c.resourceMap.Store(instance.Name, instanceResource)
+			items = append(items, Item{
+				Name:        instance.Name,
+				FullName:    relativeName(instance.SelfLink),
+				Location:    zone,
+				Labels:      instance.Labels,
+				CreatedAt:   parseTimestamp(instance.CreationTimestamp),
+				Fingerprint: computeID(instance.Id),
+			})
 		}
 	}
-	return c.ToSlice()
-}
-
-// Dependencies - Returns a List of resource names to check for
-func (c *ComputeInstances) Dependencies() []string {
-	return []string{}
+	return items, page.NextPageToken, nil
 }
 
-// Remove -
-func (c *ComputeInstances) Remove() error {
-	// Removal logic
-	errs, _ := errgroup.WithContext(c.base.config.Context)
-
-	c.resourceMap.Range(func(key, value interface{}) bool {
-		instanceID := key.(string)
-		zone := value.(DefaultResourceProperties).zone
-
-		// Check if a resource is exclued from deletion
-		if slices.Contains(c.base.config.Exclusions.ComputeInstance, instanceID) {
-			log.Printf("[Info] Excluded resource: %v (%v)", instanceID, c.Name())
-			// This instanceID is excluded from deletion, returning
-			return false
+// deleteOne - sets the attached disks to be deleted with the instance, then starts deleting the instance
+func (c *ComputeInstances) deleteOne(ctx context.Context, b *ResourceBase, client *compute.Service, item Item) (*compute.Operation, error) {
+	instance, err := client.Instances.Get(item.Project, item.Location, item.Name).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	for _, disk := range instance.Disks {
+		// Set all attached compute disks to auto delete on instance deletion
+		diskOperation, err := client.Instances.SetDiskAutoDelete(item.Project, item.Location, item.Name, true, disk.DeviceName).Context(ctx).Do()
+		if err != nil {
+			return nil, err
 		}
-
-		// Parallel instance deletion
-		errs.Go(func() error {
-			getInstanceCall := c.serviceClient.Instances.Get(c.base.config.Project, zone, instanceID)
-			getOp, err := getInstanceCall.Do()
-			if err != nil {
-				return err
-			}
-			for _, disk := range getOp.Disks {
-				// Set all attached compute disks to auto delete on instance deletion
-				diskSetCall := c.serviceClient.Instances.SetDiskAutoDelete(c.base.config.Project, zone, instanceID, true, disk.DeviceName)
-				// Todo - check this op until it completes, most likely not needed, but always nice to be safe
-				_, err := diskSetCall.Do()
-				if err != nil {
-					return err
-				}
-			}
-			deleteCall := c.serviceClient.Instances.Delete(c.base.config.Project, zone, instanceID)
-			operation, err := deleteCall.Do()
-			if err != nil {
-				return err
-			}
-			var opStatus string
-			seconds := 0
-			for opStatus != "DONE" {
-				log.Printf("[Info] Resource currently being deleted %v [type: %v project: %v zone: %v] (%v seconds)", instanceID, c.Name(), c.base.config.Project, zone, seconds)
-
-				operationCall := c.serviceClient.ZoneOperations.Get(c.base.config.Project, zone, operation.Name)
-				checkOpp, err := operationCall.Do()
-				if err != nil {
-					return err
-				}
-				opStatus = checkOpp.Status
-
-				time.Sleep(time.Duration(c.base.config.PollTime) * time.Second)
-				seconds += c.base.config.PollTime
-				if seconds > c.base.config.Timeout {
-					return fmt.Errorf("[Error] Resource deletion timed out for %v [type: %v project: %v zone: %v] (%v seconds)", instanceID, c.Name(), c.base.config.Project, zone, c.base.config.Timeout)
-				}
-			}
-			c.resourceMap.Delete(instanceID)
-
-			log.Printf("[Info] Resource deleted %v [type: %v project: %v zone: %v] (%v seconds)", instanceID, c.Name(), c.base.config.Project, zone, seconds)
-			return nil
-		})
-
-		return true
-	})
-	// Wait for all deletions to complete, and return the first non nil error
-	err := errs.Wait()
-	return err
+		if _, err := b.waitForComputeOperation(ctx, item, client, diskOperation); err != nil {
+			return nil, err
+		}
+	}
+	return client.Instances.Delete(item.Project, item.Location, item.Name).Context(ctx).Do()
 }
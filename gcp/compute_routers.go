@@ -1,25 +1,14 @@
 package gcp
 
 import (
-	"fmt"
-	"log"
-	"sync"
-	"time"
+	"context"
 
-	"github.com/BESTSELLER/gcp-nuke/config"
-	"github.com/BESTSELLER/gcp-nuke/helpers"
-	"golang.org/x/exp/slices"
-	"golang.org/x/sync/errgroup"
-	"golang.org/x/sync/syncmap"
 	"google.golang.org/api/compute/v1"
-	"google.golang.org/api/option"
 )
 
 // ComputeRouters -
 type ComputeRouters struct {
-	serviceClient *compute.Service
-	base          ResourceBase
-	resourceMap   syncmap.Map
+	computeDefinition
 }
 
 // Name - Name of the resourceLister for ComputeRouters
@@ -27,45 +16,19 @@ func (c *ComputeRouters) Name() string {
 	return "ComputeRouters"
 }
 
-// ToSlice - Name of the resourceLister for ComputeRouters
-func (c *ComputeRouters) ToSlice() (slice []string) {
-	return helpers.SortedSyncMapKeys(&c.resourceMap)
+// Service - API that must be enabled for ComputeRouters
+func (c *ComputeRouters) Service() string {
+	return "compute.googleapis.com"
 }
 
-// Setup - populates the struct
-func (c *ComputeRouters) Setup(config config.Config) {
-	c.base.config = config
-
-	computeService, err := compute.NewService(Ctx, option.WithTokenSource(config.GCPToken))
-	if err != nil {
-		log.Fatalf("ComputeRouters.Setup.NewService: %s", err)
-	}
-	computeResource := ComputeRouters{
-		serviceClient: computeService,
-	}
-	register(&computeResource)
+// ConfigKey - key of the ComputeRouters exclusions in the config file
+func (c *ComputeRouters) ConfigKey() string {
+	return "compute_router"
 }
 
-// List - Returns a list of all ComputeRouters
-func (c *ComputeRouters) List(refreshCache bool) []string {
-	if !refreshCache {
-		return c.ToSlice()
-	}
-	// Refresh resource map
-	c.resourceMap = sync.Map{}
-
-	for _, region := range c.base.config.Regions {
-		routerListCall := c.serviceClient.Routers.List(c.base.config.Project, region)
-		routerList, err := routerListCall.Do()
-		if err != nil {
-			log.Fatalf("ComputeRouters.List: %s", err)
-		}
-
-		for _, router := range routerList.Items {
-			c.resourceMap.Store(router.Name, region)
-		}
-	}
-	return c.ToSlice()
+// Filters - filters ComputeRouters items can be selected by
+func (c *ComputeRouters) Filters() FilterSupport {
+	return FilterSupport{Labels: false, Age: true}
 }
 
 // Dependencies - Returns a List of resource names to check for
@@ -75,55 +38,33 @@ func (c *ComputeRouters) Dependencies() []string {
 	return []string{a.Name(), b.Name()}
 }
 
-// Remove -
-func (c *ComputeRouters) Remove() error {
-	// Removal logic
-	errs, _ := errgroup.WithContext(c.base.config.Context)
-
-	c.resourceMap.Range(func(key, value interface{}) bool {
-		routerID := key.(string)
-		region := value.(string)
-
-		// Check if a resource is exclued from deletion
-		if slices.Contains(c.base.config.Exclusions.ComputeRouter, routerID) {
-			log.Printf("[Info] Excluded resource: %v (%v)", routerID, c.Name())
-			// This instanceID is excluded from deletion, returning
-			return false
+// listPage - lists one page of the routers in the configured regions
+func (c *ComputeRouters) listPage(ctx context.Context, b *ResourceBase, client *compute.Service, pageToken string) ([]Item, string, error) {
+	page, err := client.Routers.AggregatedList(b.config.Project).PageToken(pageToken).Context(ctx).Do()
+	if err != nil {
+		return nil, "", err
+	}
+	items := []Item{}
+	for scope, scopedList := range page.Items {
+		region, inScope := aggregatedScope(scope, "regions", b.config.Regions)
+		if !inScope {
+			continue
 		}
+		for _, router := range scopedList.Routers {
+			items = append(items, Item{
+				Name:        router.Name,
+				FullName:    relativeName(router.SelfLink),
+				Location:    region,
+				CreatedAt:   parseTimestamp(router.CreationTimestamp),
+				Parents:     relativeNames(router.Network),
+				Fingerprint: computeID(router.Id),
+			})
+		}
+	}
+	return items, page.NextPageToken, nil
+}
 
-		// Parallel router deletion
-		errs.Go(func() error {
-			deleteCall := c.serviceClient.Routers.Delete(c.base.config.Project, region, routerID)
-			operation, err := deleteCall.Do()
-			if err != nil {
-				return err
-			}
-			var opStatus string
-			seconds := 0
-			for opStatus != "DONE" {
-				log.Printf("[Info] Resource currently being deleted %v [type: %v project: %v] (%v seconds)", routerID, c.Name(), c.base.config.Project, seconds)
-
-				operationCall := c.serviceClient.RegionOperations.Get(c.base.config.Project, region, operation.Name)
-				checkOpp, err := operationCall.Do()
-				if err != nil {
-					return err
-				}
-				opStatus = checkOpp.Status
-
-				time.Sleep(time.Duration(c.base.config.PollTime) * time.Second)
-				seconds += c.base.config.PollTime
-				if seconds > c.base.config.Timeout {
-					return fmt.Errorf("[Error] Resource deletion timed out for %v [type: %v project: %v] (%v seconds)", routerID, c.Name(), c.base.config.Project, c.base.config.Timeout)
-				}
-			}
-			c.resourceMap.Delete(routerID)
-
-			log.Printf("[Info] Resource deleted %v [type: %v project: %v] (%v seconds)", routerID, c.Name(), c.base.config.Project, seconds)
-			return nil
-		})
-		return true
-	})
-	// Wait for all deletions to complete, and return the first non nil error
-	err := errs.Wait()
-	return err
+// deleteOne - starts deleting a router
+func (c *ComputeRouters) deleteOne(ctx context.Context, b *ResourceBase, client *compute.Service, item Item) (*compute.Operation, error) {
+	return client.Routers.Delete(item.Project, item.Location, item.Name).Context(ctx).Do()
 }
@@ -1,25 +1,14 @@
 package gcp
 
 import (
-	"fmt"
-	"log"
-	"sync"
-	"time"
+	"context"
 
-	"github.com/BESTSELLER/gcp-nuke/config"
-	"github.com/BESTSELLER/gcp-nuke/helpers"
-	"golang.org/x/exp/slices"
-	"golang.org/x/sync/errgroup"
-	"golang.org/x/sync/syncmap"
 	"google.golang.org/api/compute/v1"
-	"google.golang.org/api/option"
 )
 
 // ComputeSubnetworks -
 type ComputeSubnetworks struct {
-	serviceClient *compute.Service
-	base          ResourceBase
-	resourceMap   syncmap.Map
+	computeDefinition
 }
 
 // Name - Name of the resourceLister for ComputeSubnetworks
@@ -27,45 +16,19 @@ func (c *ComputeSubnetworks) Name() string {
 	return "ComputeSubnetworks"
 }
 
-// ToSlice - Name of the resourceLister for ComputeSubnetworks
-func (c *ComputeSubnetworks) ToSlice() (slice []string) {
-	return helpers.SortedSyncMapKeys(&c.resourceMap)
+// Service - API that must be enabled for ComputeSubnetworks
+func (c *ComputeSubnetworks) Service() string {
+	return "compute.googleapis.com"
 }
 
-// Setup - populates the struct
-func (c *ComputeSubnetworks) Setup(config config.Config) {
-	c.base.config = config
-
-	computeService, err := compute.NewService(Ctx, option.WithTokenSource(config.GCPToken))
-	if err != nil {
-		log.Fatalf("ComputeSubnetworks.Setup.NewService: %s", err)
-	}
-	computeResource := ComputeSubnetworks{
-		serviceClient: computeService,
-	}
-	register(&computeResource)
+// ConfigKey - key of the ComputeSubnetworks exclusions in the config file
+func (c *ComputeSubnetworks) ConfigKey() string {
+	return "compute_subnetwork"
 }
 
-// List - Returns a list of all ComputeSubnetworks
-func (c *ComputeSubnetworks) List(refreshCache bool) []string {
-	if !refreshCache {
-		return c.ToSlice()
-	}
-	// Refresh resource map
-	c.resourceMap = sync.Map{}
-
-	for _, region := range c.base.config.Regions {
-		subnetworkListCall := c.serviceClient.Subnetworks.List(c.base.config.Project, region)
-		subnetworkList, err := subnetworkListCall.Do()
-		if err != nil {
-			log.Fatalf("ComputeSubnetworks.List: %s", err)
-		}
-
-		for _, subnetwork := range subnetworkList.Items {
-			c.resourceMap.Store(subnetwork.Name, region)
-		}
-	}
-	return c.ToSlice()
+// Filters - filters ComputeSubnetworks items can be selected by
+func (c *ComputeSubnetworks) Filters() FilterSupport {
+	return FilterSupport{Labels: false, Age: true}
 }
 
 // Dependencies - Returns a List of resource names to check for
@@ -76,55 +39,33 @@ func (c *ComputeSubnetworks) Dependencies() []string {
 	return []string{a.Name(), b.Name(), cl.Name()}
 }
 
-// Remove -
-func (c *ComputeSubnetworks) Remove() error {
-	// Removal logic
-	errs, _ := errgroup.WithContext(c.base.config.Context)
-
-	c.resourceMap.Range(func(key, value interface{}) bool {
-		subnetworkID := key.(string)
-		region := value.(string)
-
-		// Check if a resource is exclued from deletion
-		if slices.Contains(c.base.config.Exclusions.ComputeSubNetwork, subnetworkID) {
-			log.Printf("[Info] Excluded resource: %v (%v)", subnetworkID, c.Name())
-			// This instanceID is excluded from deletion, returning
-			return false
+// listPage - lists one page of the subnetworks in the configured regions
+func (c *ComputeSubnetworks) listPage(ctx context.Context, b *ResourceBase, client *compute.Service, pageToken string) ([]Item, string, error) {
+	page, err := client.Subnetworks.AggregatedList(b.config.Project).PageToken(pageToken).Context(ctx).Do()
+	if err != nil {
+		return nil, "", err
+	}
+	items := []Item{}
+	for scope, scopedList := range page.Items {
+		region, inScope := aggregatedScope(scope, "regions", b.config.Regions)
+		if !inScope {
+			continue
 		}
+		for _, subnetwork := range scopedList.Subnetworks {
+			items = append(items, Item{
+				Name:        subnetwork.Name,
+				FullName:    relativeName(subnetwork.SelfLink),
+				Location:    region,
+				CreatedAt:   parseTimestamp(subnetwork.CreationTimestamp),
+				Parents:     relativeNames(subnetwork.Network),
+				Fingerprint: computeID(subnetwork.Id),
+			})
+		}
+	}
+	return items, page.NextPageToken, nil
+}
 
-		// Parallel subnetwork deletion
-		errs.Go(func() error {
-			deleteCall := c.serviceClient.Subnetworks.Delete(c.base.config.Project, region, subnetworkID)
-			operation, err := deleteCall.Do()
-			if err != nil {
-				return err
-			}
-			var opStatus string
-			seconds := 0
-			for opStatus != "DONE" {
-				log.Printf("[Info] Resource currently being deleted %v [type: %v project: %v] (%v seconds)", subnetworkID, c.Name(), c.base.config.Project, seconds)
-
-				operationCall := c.serviceClient.RegionOperations.Get(c.base.config.Project, region, operation.Name)
-				checkOpp, err := operationCall.Do()
-				if err != nil {
-					return err
-				}
-				opStatus = checkOpp.Status
-
-				time.Sleep(time.Duration(c.base.config.PollTime) * time.Second)
-				seconds += c.base.config.PollTime
-				if seconds > c.base.config.Timeout {
-					return fmt.Errorf("[Error] Resource deletion timed out for %v [type: %v project: %v] (%v seconds)", subnetworkID, c.Name(), c.base.config.Project, c.base.config.Timeout)
-				}
-			}
-			c.resourceMap.Delete(subnetworkID)
-
-			log.Printf("[Info] Resource deleted %v [type: %v project: %v] (%v seconds)", subnetworkID, c.Name(), c.base.config.Project, seconds)
-			return nil
-		})
-		return true
-	})
-	// Wait for all deletions to complete, and return the first non nil error
-	err := errs.Wait()
-	return err
+// deleteOne - starts deleting a subnetwork
+func (c *ComputeSubnetworks) deleteOne(ctx context.Context, b *ResourceBase, client *compute.Service, item Item) (*compute.Operation, error) {
+	return client.Subnetworks.Delete(item.Project, item.Location, item.Name).Context(ctx).Do()
 }
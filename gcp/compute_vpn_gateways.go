@@ -1,25 +1,14 @@
 package gcp
 
 import (
-	"fmt"
-	"log"
-	"sync"
-	"time"
+	"context"
 
-	"github.com/BESTSELLER/gcp-nuke/config"
-	"github.com/BESTSELLER/gcp-nuke/helpers"
-	"golang.org/x/exp/slices"
-	"golang.org/x/sync/errgroup"
-	"golang.org/x/sync/syncmap"
 	"google.golang.org/api/compute/v1"
-	"google.golang.org/api/option"
 )
 
 // ComputeVPNGateways -
 type ComputeVPNGateways struct {
-	serviceClient *compute.Service
-	base          ResourceBase
-	resourceMap   syncmap.Map
+	computeDefinition
 }
 
 // Name - Name of the resourceLister for ComputeVPNGateways
@@ -27,45 +16,19 @@ func (c *ComputeVPNGateways) Name() string {
 	return "ComputeVPNGateways"
 }
 
-// ToSlice - Name of the resourceLister for ComputeVPNGateways
-func (c *ComputeVPNGateways) ToSlice() (slice []string) {
-	return helpers.SortedSyncMapKeys(&c.resourceMap)
+// Service - API that must be enabled for ComputeVPNGateways
+func (c *ComputeVPNGateways) Service() string {
+	return "compute.googleapis.com"
 }
 
-// Setup - populates the struct
-func (c *ComputeVPNGateways) Setup(config config.Config) {
-	c.base.config = config
-
-	computeService, err := compute.NewService(Ctx, option.WithTokenSource(config.GCPToken))
-	if err != nil {
-		log.Fatalf("ComputeVPNGateways.Setup.NewService: %s", err)
-	}
-	computeResource := ComputeVPNGateways{
-		serviceClient: computeService,
-	}
-	register(&computeResource)
+// ConfigKey - key of the ComputeVPNGateways exclusions in the config file
+func (c *ComputeVPNGateways) ConfigKey() string {
+	return "compute_vpn_gateway"
 }
 
-// List - Returns a list of all ComputeVPNGateways
-func (c *ComputeVPNGateways) List(refreshCache bool) []string {
-	if !refreshCache {
-		return c.ToSlice()
-	}
-	// Refresh resource map
-	c.resourceMap = sync.Map{}
-
-	for _, region := range c.base.config.Regions {
-		gatewayListCall := c.serviceClient.VpnGateways.List(c.base.config.Project, region)
-		gatewayList, err := gatewayListCall.Do()
-		if err != nil {
-			log.Fatalf("ComputeVPNGateways.List: %s", err)
-		}
-
-		for _, gateway := range gatewayList.Items {
-			c.resourceMap.Store(gateway.Name, region)
-		}
-	}
-	return c.ToSlice()
+// Filters - filters ComputeVPNGateways items can be selected by
+func (c *ComputeVPNGateways) Filters() FilterSupport {
+	return FilterSupport{Labels: true, Age: true}
 }
 
 // Dependencies - Returns a List of resource names to check for
@@ -74,55 +37,34 @@ func (c *ComputeVPNGateways) Dependencies() []string {
 	return []string{a.Name()}
 }
 
-// Remove -
-func (c *ComputeVPNGateways) Remove() error {
-	// Removal logic
-	errs, _ := errgroup.WithContext(c.base.config.Context)
-
-	c.resourceMap.Range(func(key, value interface{}) bool {
-		gatewayID := key.(string)
-		region := value.(string)
-
-		// Check if a resource is exclued from deletion
-		if slices.Contains(c.base.config.Exclusions.ComputeVPNGateway, gatewayID) {
-			log.Printf("[Info] Excluded resource: %v (%v)", gatewayID, c.Name())
-			// This instanceID is excluded from deletion, returning
-			return false
+// listPage - lists one page of the VPN gateways in the configured regions
+func (c *ComputeVPNGateways) listPage(ctx context.Context, b *ResourceBase, client *compute.Service, pageToken string) ([]Item, string, error) {
+	page, err := client.VpnGateways.AggregatedList(b.config.Project).PageToken(pageToken).Context(ctx).Do()
+	if err != nil {
+		return nil, "", err
+	}
+	items := []Item{}
+	for scope, scopedList := range page.Items {
+		region, inScope := aggregatedScope(scope, "regions", b.config.Regions)
+		if !inScope {
+			continue
 		}
+		for _, gateway := range scopedList.VpnGateways {
+			items = append(items, Item{
+				Name:        gateway.Name,
+				FullName:    relativeName(gateway.SelfLink),
+				Location:    region,
+				Labels:      gateway.Labels,
+				CreatedAt:   parseTimestamp(gateway.CreationTimestamp),
+				Parents:     relativeNames(gateway.Network),
+				Fingerprint: computeID(gateway.Id),
+			})
+		}
+	}
+	return items, page.NextPageToken, nil
+}
 
-		// Parallel gateway deletion
-		errs.Go(func() error {
-			deleteCall := c.serviceClient.VpnGateways.Delete(c.base.config.Project, region, gatewayID)
-			operation, err := deleteCall.Do()
-			if err != nil {
-				return err
-			}
-			var opStatus string
-			seconds := 0
-			for opStatus != "DONE" {
-				log.Printf("[Info] Resource currently being deleted %v [type: %v project: %v] (%v seconds)", gatewayID, c.Name(), c.base.config.Project, seconds)
-
-				operationCall := c.serviceClient.RegionOperations.Get(c.base.config.Project, region, operation.Name)
-				checkOpp, err := operationCall.Do()
-				if err != nil {
-					return err
-				}
-				opStatus = checkOpp.Status
-
-				time.Sleep(time.Duration(c.base.config.PollTime) * time.Second)
-				seconds += c.base.config.PollTime
-				if seconds > c.base.config.Timeout {
-					return fmt.Errorf("[Error] Resource deletion timed out for %v [type: %v project: %v] (%v seconds)", gatewayID, c.Name(), c.base.config.Project, c.base.config.Timeout)
-				}
-			}
-			c.resourceMap.Delete(gatewayID)
-
-			log.Printf("[Info] Resource deleted %v [type: %v project: %v] (%v seconds)", gatewayID, c.Name(), c.base.config.Project, seconds)
-			return nil
-		})
-		return true
-	})
-	// Wait for all deletions to complete, and return the first non nil error
-	err := errs.Wait()
-	return err
+// deleteOne - starts deleting a VPN gateway
+func (c *ComputeVPNGateways) deleteOne(ctx context.Context, b *ResourceBase, client *compute.Service, item Item) (*compute.Operation, error) {
+	return client.VpnGateways.Delete(item.Project, item.Location, item.Name).Context(ctx).Do()
 }
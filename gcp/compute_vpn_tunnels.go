@@ -1,25 +1,14 @@
 package gcp
 
 import (
-	"fmt"
-	"log"
-	"sync"
-	"time"
+	"context"
 
-	"github.com/BESTSELLER/gcp-nuke/config"
-	"github.com/BESTSELLER/gcp-nuke/helpers"
-	"golang.org/x/exp/slices"
-	"golang.org/x/sync/errgroup"
-	"golang.org/x/sync/syncmap"
 	"google.golang.org/api/compute/v1"
-	"google.golang.org/api/option"
 )
 
 // ComputeVPNTunnels -
 type ComputeVPNTunnels struct {
-	serviceClient *compute.Service
-	base          ResourceBase
-	resourceMap   syncmap.Map
+	computeDefinition
 }
 
 // Name - Name of the resourceLister for ComputeVPNTunnels
@@ -27,45 +16,19 @@ func (c *ComputeVPNTunnels) Name() string {
 	return "ComputeVPNTunnels"
 }
 
-// ToSlice - Name of the resourceLister for ComputeVPNTunnels
-func (c *ComputeVPNTunnels) ToSlice() (slice []string) {
-	return helpers.SortedSyncMapKeys(&c.resourceMap)
+// Service - API that must be enabled for ComputeVPNTunnels
+func (c *ComputeVPNTunnels) Service() string {
+	return "compute.googleapis.com"
 }
 
-// Setup - populates the struct
-func (c *ComputeVPNTunnels) Setup(config config.Config) {
-	c.base.config = config
-
-	computeService, err := compute.NewService(Ctx, option.WithTokenSource(config.GCPToken))
-	if err != nil {
-		log.Fatalf("ComputeVPNTunnels.Setup.NewService: %s", err)
-	}
-	computeResource := ComputeVPNTunnels{
-		serviceClient: computeService,
-	}
-	register(&computeResource)
+// ConfigKey - key of the ComputeVPNTunnels exclusions in the config file
+func (c *ComputeVPNTunnels) ConfigKey() string {
+	return "compute_vpn_tunnel"
 }
 
-// List - Returns a list of all ComputeVPNTunnels
-func (c *ComputeVPNTunnels) List(refreshCache bool) []string {
-	if !refreshCache {
-		return c.ToSlice()
-	}
-	// Refresh resource map
-	c.resourceMap = sync.Map{}
-
-	for _, region := range c.base.config.Regions {
-		tunnelListCall := c.serviceClient.VpnTunnels.List(c.base.config.Project, region)
-		tunnelList, err := tunnelListCall.Do()
-		if err != nil {
-			log.Fatalf("ComputeVPNTunnels.List: %s", err)
-		}
-
-		for _, tunnel := range tunnelList.Items {
-			c.resourceMap.Store(tunnel.Name, region)
-		}
-	}
-	return c.ToSlice()
+// Filters - filters ComputeVPNTunnels items can be selected by
+func (c *ComputeVPNTunnels) Filters() FilterSupport {
+	return FilterSupport{Labels: true, Age: true}
 }
 
 // Dependencies - Returns a List of resource names to check for
@@ -73,55 +36,34 @@ func (c *ComputeVPNTunnels) Dependencies() []string {
 	return []string{}
 }
 
-// Remove -
-func (c *ComputeVPNTunnels) Remove() error {
-	// Removal logic
-	errs, _ := errgroup.WithContext(c.base.config.Context)
-
-	c.resourceMap.Range(func(key, value interface{}) bool {
-		tunnelID := key.(string)
-		region := value.(string)
-
-		// Check if a resource is exclued from deletion
-		if slices.Contains(c.base.config.Exclusions.ComputeVPNTunnel, tunnelID) {
-			log.Printf("[Info] Excluded resource: %v (%v)", tunnelID, c.Name())
-			// This instanceID is excluded from deletion, returning
-			return false
+// listPage - lists one page of the VPN tunnels in the configured regions
+func (c *ComputeVPNTunnels) listPage(ctx context.Context, b *ResourceBase, client *compute.Service, pageToken string) ([]Item, string, error) {
+	page, err := client.VpnTunnels.AggregatedList(b.config.Project).PageToken(pageToken).Context(ctx).Do()
+	if err != nil {
+		return nil, "", err
+	}
+	items := []Item{}
+	for scope, scopedList := range page.Items {
+		region, inScope := aggregatedScope(scope, "regions", b.config.Regions)
+		if !inScope {
+			continue
 		}
+		for _, tunnel := range scopedList.VpnTunnels {
+			items = append(items, Item{
+				Name:        tunnel.Name,
+				FullName:    relativeName(tunnel.SelfLink),
+				Location:    region,
+				Labels:      tunnel.Labels,
+				CreatedAt:   parseTimestamp(tunnel.CreationTimestamp),
+				Parents:     relativeNames(tunnel.VpnGateway, tunnel.TargetVpnGateway),
+				Fingerprint: computeID(tunnel.Id),
+			})
+		}
+	}
+	return items, page.NextPageToken, nil
+}
 
-		// Parallel tunnel deletion
-		errs.Go(func() error {
-			deleteCall := c.serviceClient.VpnTunnels.Delete(c.base.config.Project, region, tunnelID)
-			operation, err := deleteCall.Do()
-			if err != nil {
-				return err
-			}
-			var opStatus string
-			seconds := 0
-			for opStatus != "DONE" {
-				log.Printf("[Info] Resource currently being deleted %v [type: %v project: %v] (%v seconds)", tunnelID, c.Name(), c.base.config.Project, seconds)
-
-				operationCall := c.serviceClient.RegionOperations.Get(c.base.config.Project, region, operation.Name)
-				checkOpp, err := operationCall.Do()
-				if err != nil {
-					return err
-				}
-				opStatus = checkOpp.Status
-
-				time.Sleep(time.Duration(c.base.config.PollTime) * time.Second)
-				seconds += c.base.config.PollTime
-				if seconds > c.base.config.Timeout {
-					return fmt.Errorf("[Error] Resource deletion timed out for %v [type: %v project: %v] (%v seconds)", tunnelID, c.Name(), c.base.config.Project, c.base.config.Timeout)
-				}
-			}
-			c.resourceMap.Delete(tunnelID)
-
-			log.Printf("[Info] Resource deleted %v [type: %v project: %v] (%v seconds)", tunnelID, c.Name(), c.base.config.Project, seconds)
-			return nil
-		})
-		return true
-	})
-	// Wait for all deletions to complete, and return the first non nil error
-	err := errs.Wait()
-	return err
+// deleteOne - starts deleting a VPN tunnel
+func (c *ComputeVPNTunnels) deleteOne(ctx context.Context, b *ResourceBase, client *compute.Service, item Item) (*compute.Operation, error) {
+	return client.VpnTunnels.Delete(item.Project, item.Location, item.Name).Context(ctx).Do()
 }
@@ -1,25 +1,14 @@
 package gcp
 
 import (
-	"fmt"
-	"log"
-	"sync"
-	"time"
+	"context"
 
-	"github.com/BESTSELLER/gcp-nuke/config"
-	"github.com/BESTSELLER/gcp-nuke/helpers"
-	"golang.org/x/exp/slices"
-	"golang.org/x/sync/errgroup"
-	"golang.org/x/sync/syncmap"
 	"google.golang.org/api/compute/v1"
-	"google.golang.org/api/option"
 )
 
 // ComputeZoneAutoScalers -
 type ComputeZoneAutoScalers struct {
-	serviceClient *compute.Service
-	base          ResourceBase
-	resourceMap   syncmap.Map
+	computeDefinition
 }
 
 // Name - Name of the resourceLister for ComputeZoneAutoScalers
@@ -27,48 +16,19 @@ func (c *ComputeZoneAutoScalers) Name() string {
 	return "ComputeZoneAutoScalers"
 }
 
-// ToSlice - Name of the resourceLister for ComputeZoneAutoScalers
-func (c *ComputeZoneAutoScalers) ToSlice() (slice []string) {
-	return helpers.SortedSyncMapKeys(&c.resourceMap)
+// Service - API that must be enabled for ComputeZoneAutoScalers
+func (c *ComputeZoneAutoScalers) Service() string {
+	return "compute.googleapis.com"
 }
 
-// Setup - populates the struct
-func (c *ComputeZoneAutoScalers) Setup(config config.Config) {
-	c.base.config = config
-
-	computeService, err := compute.NewService(Ctx, option.WithTokenSource(config.GCPToken))
-	if err != nil {
-		log.Fatalf("ComputeZoneAutoScalers.Setup.NewService: %s", err)
-	}
-	computeResource := ComputeZoneAutoScalers{
-		serviceClient: computeService,
-	}
-	register(&computeResource)
+// ConfigKey - key of the ComputeZoneAutoScalers exclusions in the config file
+func (c *ComputeZoneAutoScalers) ConfigKey() string {
+	return "compute_zone_autoscaler"
 }
 
-// List - Returns a list of all ComputeZoneAutoScalers
-func (c *ComputeZoneAutoScalers) List(refreshCache bool) []string {
-	if !refreshCache {
-		return c.ToSlice()
-	}
-	// Refresh resource map
-	c.resourceMap = sync.Map{}
-
-	for _, zone := range c.base.config.Zones {
-		instanceListCall := c.serviceClient.Autoscalers.List(c.base.config.Project, zone)
-		instanceList, err := instanceListCall.Do()
-		if err != nil {
-			log.Fatalf("ComputeZoneAutoScalers.List: %s", err)
-		}
-
-		for _, instance := range instanceList.Items {
-			instanceResource := DefaultResourceProperties{
-				zone: zone,
-			}
-			c.resourceMap.Store(instance.Name, instanceResource)
-		}
-	}
-	return c.ToSlice()
+// Filters - filters ComputeZoneAutoScalers items can be selected by
+func (c *ComputeZoneAutoScalers) Filters() FilterSupport {
+	return FilterSupport{Labels: false, Age: true}
 }
 
 // Dependencies - Returns a List of resource names to check for
@@ -76,55 +36,33 @@ func (c *ComputeZoneAutoScalers) Dependencies() []string {
 	return []string{}
 }
 
-// Remove -
-func (c *ComputeZoneAutoScalers) Remove() error {
-	// Removal logic
-	errs, _ := errgroup.WithContext(c.base.config.Context)
-
-	c.resourceMap.Range(func(key, value interface{}) bool {
-		instanceID := key.(string)
-		zone := value.(DefaultResourceProperties).zone
-
-		// Check if a resource is exclued from deletion
-		if slices.Contains(c.base.config.Exclusions.ComputeZoneAutoscaler, instanceID) {
-			log.Printf("[Info] Excluded resource: %v (%v)", instanceID, c.Name())
-			// This instanceID is excluded from deletion, returning
-			return false
+// listPage - lists one page of the autoscalers in the configured zones
+func (c *ComputeZoneAutoScalers) listPage(ctx context.Context, b *ResourceBase, client *compute.Service, pageToken string) ([]Item, string, error) {
+	page, err := client.Autoscalers.AggregatedList(b.config.Project).PageToken(pageToken).Context(ctx).Do()
+	if err != nil {
+		return nil, "", err
+	}
+	items := []Item{}
+	for scope, scopedList := range page.Items {
+		zone, inScope := aggregatedScope(scope, "zones", b.config.Zones)
+		if !inScope {
+			continue
 		}
+		for _, instance := range scopedList.Autoscalers {
+			items = append(items, Item{
+				Name:        instance.Name,
+				FullName:    relativeName(instance.SelfLink),
+				Location:    zone,
+				CreatedAt:   parseTimestamp(instance.CreationTimestamp),
+				Parents:     relativeNames(instance.Target),
+				Fingerprint: computeID(instance.Id),
+			})
+		}
+	}
+	return items, page.NextPageToken, nil
+}
 
-		// Parallel instance deletion
-		errs.Go(func() error {
-			deleteCall := c.serviceClient.Autoscalers.Delete(c.base.config.Project, zone, instanceID)
-			operation, err := deleteCall.Do()
-			if err != nil {
-				return err
-			}
-			var opStatus string
-			seconds := 0
-			for opStatus != "DONE" {
-				log.Printf("[Info] Resource currently being deleted %v [type: %v project: %v zone: %v] (%v seconds)", instanceID, c.Name(), c.base.config.Project, zone, seconds)
-
-				operationCall := c.serviceClient.ZoneOperations.Get(c.base.config.Project, zone, operation.Name)
-				checkOpp, err := operationCall.Do()
-				if err != nil {
-					return err
-				}
-				opStatus = checkOpp.Status
-
-				time.Sleep(time.Duration(c.base.config.PollTime) * time.Second)
-				seconds += c.base.config.PollTime
-				if seconds > c.base.config.Timeout {
-					return fmt.Errorf("[Error] Resource deletion timed out for %v [type: %v project: %v zone: %v] (%v seconds)", instanceID, c.Name(), c.base.config.Project, zone, c.base.config.Timeout)
-				}
-			}
-			c.resourceMap.Delete(instanceID)
-
-			log.Printf("[Info] Resource deleted %v [type: %v project: %v zone: %v] (%v seconds)", instanceID, c.Name(), c.base.config.Project, zone, seconds)
-			return nil
-		})
-		return true
-	})
-	// Wait for all deletions to complete, and return the first non nil error
-	err := errs.Wait()
-	return err
+// deleteOne - starts deleting a zonal autoscaler
+func (c *ComputeZoneAutoScalers) deleteOne(ctx context.Context, b *ResourceBase, client *compute.Service, item Item) (*compute.Operation, error) {
+	return client.Autoscalers.Delete(item.Project, item.Location, item.Name).Context(ctx).Do()
 }
@@ -1,75 +1,34 @@
 package gcp
 
 import (
+	"context"
 	"fmt"
-	"log"
-	"strings"
-	"sync"
-	"time"
 
-	"github.com/BESTSELLER/gcp-nuke/config"
-	"github.com/BESTSELLER/gcp-nuke/helpers"
-	"golang.org/x/exp/slices"
-	"golang.org/x/sync/errgroup"
-	"golang.org/x/sync/syncmap"
 	"google.golang.org/api/container/v1"
 	"google.golang.org/api/option"
 )
 
 // ContainerGKEClusters -
-type ContainerGKEClusters struct {
-	serviceClient  *container.Service
-	base           ResourceBase
-	resourceMap    syncmap.Map
-	InstanceGroups []string
-}
+type ContainerGKEClusters struct{}
 
 // Name - Name of the resourceLister for ContainerGKEClusters
 func (c *ContainerGKEClusters) Name() string {
 	return "ContainerGKEClusters"
 }
 
-// ToSlice - Name of the resourceLister for ContainerGKEClusters
-func (c *ContainerGKEClusters) ToSlice() (slice []string) {
-	return helpers.SortedSyncMapKeys(&c.resourceMap)
+// Service - API that must be enabled for ContainerGKEClusters
+func (c *ContainerGKEClusters) Service() string {
+	return "container.googleapis.com"
 }
 
-// Setup - populates the struct
-func (c *ContainerGKEClusters) Setup(config config.Config) {
-	c.base.config = config
-
-	containerService, err := container.NewService(Ctx, option.WithTokenSource(config.GCPToken))
-	if err != nil {
-		log.Fatalf("ContainerGKEClusters.Setup.NewService: %s", err)
-	}
-	containerResource := ContainerGKEClusters{
-		serviceClient: containerService,
-	}
-	register(&containerResource)
+// ConfigKey - key of the ContainerGKEClusters exclusions in the config file
+func (c *ContainerGKEClusters) ConfigKey() string {
+	return "container_gke_cluster"
 }
 
-// List - Returns a list of all ContainerGKEClusters
-func (c *ContainerGKEClusters) List(refreshCache bool) []string {
-	if !refreshCache {
-		return c.ToSlice()
-	}
-	// Refresh resource map
-	c.resourceMap = sync.Map{}
-
-	instanceListCall := c.serviceClient.Projects.Locations.Clusters.List(fmt.Sprintf("projects/%v/locations/-", c.base.config.Project))
-	instanceList, err := instanceListCall.Do()
-	if err != nil {
-		log.Fatalf("ContainerGKEClusters.List: %s", err)
-	}
-
-	for _, instance := range instanceList.Clusters {
-		c.appendInstanceGroups(instance.Name, instance.Location)
-		instanceResource := DefaultResourceProperties{}
-		clusterLink := extractGKESelfLink(instance.SelfLink)
-		c.resourceMap.Store(clusterLink, instanceResource)
-	}
-
-	return c.ToSlice()
+// Filters - filters ContainerGKEClusters items can be selected by
+func (c *ContainerGKEClusters) Filters() FilterSupport {
+	return FilterSupport{Labels: true, Age: true}
 }
 
 // Dependencies - Returns a List of resource names to check for
@@ -77,70 +36,54 @@ func (c *ContainerGKEClusters) Dependencies() []string {
 	return []string{}
 }
 
-// Remove -
-func (c *ContainerGKEClusters) Remove() error {
-	// Removal logic
-	errs, _ := errgroup.WithContext(c.base.config.Context)
-
-	c.resourceMap.Range(func(key, value interface{}) bool {
-		instanceID := key.(string)
-		location := strings.Split(instanceID, "/")[3]
-
-		// Check if a resource is exclued from deletion
-		if slices.Contains(c.base.config.Exclusions.ContainerGKECluster, instanceID) {
-			log.Printf("[Info] Excluded resource: %v (%v)", instanceID, c.Name())
-			// This instanceID is excluded from deletion, returning
-			return false
-		}
+// newClient - creates the container client
+func (c *ContainerGKEClusters) newClient(ctx context.Context, b *ResourceBase) (*container.Service, error) {
+	return container.NewService(ctx, option.WithTokenSource(b.config.GCPToken))
+}
 
-		// Parallel instance deletion
-		errs.Go(func() error {
-			deleteCall := c.serviceClient.Projects.Locations.Clusters.Delete(instanceID)
-			operation, err := deleteCall.Do()
-			if err != nil {
-				return err
-			}
-			var opStatus string
-			seconds := 0
-			for opStatus != "DONE" {
-				log.Printf("[Info] Resource currently being deleted %v [type: %v project: %v] (%v seconds)", instanceID, c.Name(), c.base.config.Project, seconds)
-				operationCall := c.serviceClient.Projects.Locations.Operations.Get(fmt.Sprintf("projects/%v/locations/%v/operations/%v", c.base.config.Project, location, operation.Name))
-				checkOpp, err := operationCall.Do()
-				if err != nil {
-					return err
-				}
-				opStatus = checkOpp.Status
+// listPage - lists the clusters of every location. The container API is not paginated, every cluster comes back in one response
+func (c *ContainerGKEClusters) listPage(ctx context.Context, b *ResourceBase, client *container.Service, pageToken string) ([]Item, string, error) {
+	clusters, err := client.Projects.Locations.Clusters.List(fmt.Sprintf("projects/%v/locations/-", b.config.Project)).Context(ctx).Do()
+	if err != nil {
+		return nil, "", err
+	}
+	items := []Item{}
+	for _, instance := range clusters.Clusters {
+		items = append(items, Item{
+			Name:        instance.Name,
+			FullName:    extractGKESelfLink(instance.SelfLink),
+			Location:    instance.Location,
+			Labels:      instance.ResourceLabels,
+			CreatedAt:   parseTimestamp(instance.CreateTime),
+			Fingerprint: instance.Id,
+		})
+	}
+	return items, "", nil
+}
 
-				time.Sleep(time.Duration(c.base.config.PollTime) * time.Second)
-				seconds += c.base.config.PollTime
-				if seconds > c.base.config.Timeout {
-					return fmt.Errorf("[Error] Resource deletion timed out for %v [type: %v project: %v] (%v seconds):\n %v", instanceID, c.Name(), c.base.config.Project, c.base.config.Timeout, err.Error())
-				}
-			}
-			c.resourceMap.Delete(instanceID)
+// deleteOne - starts deleting a cluster with its node pools
+func (c *ContainerGKEClusters) deleteOne(ctx context.Context, b *ResourceBase, client *container.Service, item Item) (*container.Operation, error) {
+	return client.Projects.Locations.Clusters.Delete(item.FullName).Context(ctx).Do()
+}
 
-			log.Printf("[Info] Resource deleted %v [type: %v project: %v] (%v seconds)", instanceID, c.Name(), c.base.config.Project, seconds)
-			return nil
-		})
-		return true
-	})
-	// Wait for all deletions to complete, and return the first non nil error
-	err := errs.Wait()
-	return err
+// waitForOp - waits for the container operation of a deletion
+func (c *ContainerGKEClusters) waitForOp(ctx context.Context, b *ResourceBase, client *container.Service, item Item, operation *container.Operation) (int, error) {
+	return b.waitForContainerOperation(ctx, item, client, operation.Name)
 }
 
-// appendInstanceGroups - keep track of instance groups - this is used by compute_instance_zone_groups to exclude any gke nodepools
-func (c *ContainerGKEClusters) appendInstanceGroups(clusterName, clusterLocation string) {
-	parentLocation := fmt.Sprintf("projects/%v/locations/%v/clusters/%v", c.base.config.Project, clusterLocation, clusterName)
-	nodePoolCall := c.serviceClient.Projects.Locations.Clusters.NodePools.List(parentLocation)
-	nodePools, err := nodePoolCall.Do()
+// nodePoolInstanceGroups - names of the instance groups of every GKE node pool in the project, also of clusters that are kept
+func nodePoolInstanceGroups(ctx context.Context, client *container.Service, project string) ([]string, error) {
+	clusters, err := client.Projects.Locations.Clusters.List(fmt.Sprintf("projects/%v/locations/-", project)).Context(ctx).Do()
 	if err != nil {
-		log.Fatalf("ContainerGKEClusters.appendInstanceGroups.NodePools.List: %s", err)
+		return nil, fmt.Errorf("nodePoolInstanceGroups: %w", err)
 	}
-	for _, nodePool := range nodePools.NodePools {
-		for _, instanceGroupURL := range nodePool.InstanceGroupUrls {
-			instanceGroupName := strings.Split(instanceGroupURL, "/instanceGroupManagers/")[1]
-			c.InstanceGroups = append(c.InstanceGroups, instanceGroupName)
+	instanceGroups := []string{}
+	for _, cluster := range clusters.Clusters {
+		for _, nodePool := range cluster.NodePools {
+			for _, instanceGroupURL := range nodePool.InstanceGroupUrls {
+				instanceGroups = append(instanceGroups, lastSegment(instanceGroupURL))
+			}
 		}
 	}
+	return instanceGroups, nil
 }
@@ -1,120 +1,402 @@
 package gcp
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
-	"strings"
+	"log/slog"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/BESTSELLER/gcp-nuke/config"
 	"github.com/BESTSELLER/gcp-nuke/helpers"
-	"golang.org/x/sync/errgroup"
+	"github.com/BESTSELLER/gcp-nuke/report"
 )
 
-// RemoveProject  -
-func RemoveProject(config config.Config) {
-	helpers.SetupCloseHandler()
-	resourceMap := GetResourceMap(config)
+// ErrInterrupted - the run was interrupted before every resource was deleted
+var ErrInterrupted = errors.New("interrupted")
 
-	// Parallel deletion
-	errs, _ := errgroup.WithContext(config.Context)
+// RemoveProject  - removes all resources known to the registry from the configured project.
+// Each resource type starts as soon as every type it depends on has finished. After the first failed deletion no further types start, unless
+// config.ContinueOnError is set. Types depending on a failed type, or on one that could not be listed, are always skipped.
+// While types fail, everything is listed and deleted again in further passes, until a pass deletes nothing or config.MaxPasses is reached.
+// Once config.Drain is done no further types start, and what was and was not deleted is logged.
+// Failed types of the last pass are returned as FailedTypes
+func RemoveProject(registry *Registry, config config.Config) error {
+	resourceMap, err := registry.Resources(config)
+	if err != nil {
+		return fmt.Errorf("RemoveProject: %s", err)
+	}
+	waves, err := dependencyWaves(resourceMap)
+	if err != nil {
+		return fmt.Errorf("RemoveProject: %s", err)
+	}
+	slog.Debug("Deletion waves", logProject, config.Project, "waves", waves)
+
+	var last *pass
+	for number := 1; ; number++ {
+		last = removePass(resourceMap, config, number)
+		if interrupted(config) || last.failedCount() == 0 || config.DryRun {
+			break
+		}
+		if last.deleted.Load() == 0 {
+			slog.Info("Pass deleted nothing, stopping", logProject, config.Project, "pass", number, "failed_types", last.failedCount())
+			break
+		}
+		if number == config.MaxPasses {
+			slog.Info("Reached the maximum number of passes, stopping", logProject, config.Project, "pass", number, "failed_types", last.failedCount())
+			break
+		}
+		slog.Info("Pass left resource types failed, starting another pass", logProject, config.Project, "pass", number, "deleted", last.deleted.Load(), "failed_types", last.failedCount(), "wait_seconds", config.PollTime)
+		select {
+		case <-drained(config):
+		case <-time.After(time.Duration(config.PollTime) * time.Second):
+		}
+	}
+	// Only the last pass says what was left, earlier ones were retried
+	last.report(config)
+
+	order, _ := dependencyOrder(resourceMap)
+	failed := &FailedTypes{Total: len(resourceMap)}
+	for _, name := range order {
+		if err, found := last.failures.Load(name); found {
+			slog.Error("Resource type failed", logProject, config.Project, logType, name, logError, err)
+			failed.Errors = append(failed.Errors, &TypeError{Type: name, Err: err.(error)})
+		}
+	}
+
+	if interrupted(config) {
+		logInterrupted(resourceMap, &last.listed, config)
+		if len(failed.Errors) > 0 {
+			return fmt.Errorf("RemoveProject: %w, %w", ErrInterrupted, failed)
+		}
+		return fmt.Errorf("RemoveProject: %w", ErrInterrupted)
+	}
+	if len(failed.Errors) > 0 {
+		logRemaining(resourceMap, last, config)
+		return fmt.Errorf("RemoveProject: %w", failed)
+	}
+
+	slog.Info("Deletion complete", logProject, config.Project, "passes", last.number, "dry_run", config.DryRun)
+	return nil
+}
+
+// pass - one dependency ordered attempt at every resource type of a project
+type pass struct {
+	number int
+	// Items of each type as first listed, types missing here were never started
+	listed sync.Map
+	// Errors of the types that failed, keyed by type
+	failures sync.Map
+	// Types skipped because a type they depend on failed or was skipped, keyed by type, holding the dependency
+	blocked sync.Map
+	// stopped - set by the first failed deletion unless the run continues on errors
+	stopped atomic.Bool
+	// deleted - number of items that are gone since they were listed
+	deleted atomic.Int64
+
+	mutex sync.Mutex
+	// entries - report entries of whole resource types, only added to the report for the last pass
+	entries []report.Entry
+}
 
+// removePass - starts every resource type once its dependencies have finished, and waits for all of them
+func removePass(resourceMap map[string]Resource, config config.Config, number int) *pass {
+	p := &pass{number: number}
+	slog.Info("Starting deletion pass", logProject, config.Project, "pass", number)
+
+	finished := make(map[string]chan struct{}, len(resourceMap))
+	for name := range resourceMap {
+		finished[name] = make(chan struct{})
+	}
+
+	// Parallel deletion
+	var wg sync.WaitGroup
 	for _, resource := range resourceMap {
 		resource := resource
-		errs.Go(func() error {
-			log.Println("[Info] Retrieving list of resources for", resource.Name())
-			resource.List(true)
-			if config.DryRun {
-				parallelDryRun(resource, config)
-				return nil
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(finished[resource.Name()])
+			for _, dependency := range resource.Dependencies() {
+				<-finished[dependency]
 			}
-			err := parallelResourceDeletion(resourceMap, resource, config)
-			if err != nil {
-				return err
+			if interrupted(config) {
+				return
 			}
-			return nil
-		})
+			if p.stopped.Load() {
+				slog.Info("Skipped resource type after an earlier failure", typeLog(resource, config.Project)...)
+				p.skipType(resource, config, "earlier failure")
+				return
+			}
+			for _, dependency := range resource.Dependencies() {
+				_, failed := p.failures.Load(dependency)
+				_, skipped := p.blocked.Load(dependency)
+				if failed || skipped {
+					// Its items would still be in use by those of the dependency
+					slog.Info("Skipped resource type, a dependency was not deleted", append(typeLog(resource, config.Project), "dependency", dependency)...)
+					p.skipType(resource, config, "dependency failed")
+					p.blocked.Store(resource.Name(), dependency)
+					return
+				}
+			}
+			if err := p.removeResourceType(resource, config); err != nil {
+				p.failures.Store(resource.Name(), err)
+				// A type that could not be listed only holds up its dependents, like every failure with ContinueOnError
+				if _, listed := p.listed.Load(resource.Name()); listed && !config.ContinueOnError {
+					p.stopped.Store(true)
+				}
+			}
+		}()
 	}
 
-	// Wait for all deletions to complete, and check for errors
-	if err := errs.Wait(); err != nil {
-		log.Fatalf("RemoveProject: %s", err)
-	}
+	// Wait for all deletions to complete
+	wg.Wait()
+	return p
+}
 
-	log.Printf("-- Deletion complete for project %v (dry-run: %v) --\n", config.Project, config.DryRun)
+// failedCount - number of resource types that failed in the pass
+func (p *pass) failedCount() int {
+	count := 0
+	p.failures.Range(func(_, _ any) bool {
+		count++
+		return true
+	})
+	return count
 }
 
-func parallelResourceDeletion(resourceMap map[string]Resource, resource Resource, config config.Config) error {
-	refreshCache := false
-	if len(resource.List(false)) == 0 {
-		log.Println("[Skipping] No", resource.Name(), "items to delete")
+// skipType - notes a resource type that was never started
+func (p *pass) skipType(resource Resource, config config.Config, reason string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.entries = append(p.entries, report.Entry{
+		Type:    resource.Name(),
+		Project: config.Project,
+		Action:  report.Skipped,
+		Reason:  reason,
+	})
+}
+
+// report - adds the entries of whole resource types to the run report
+func (p *pass) report(config config.Config) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for _, entry := range p.entries {
+		config.Report.Add(entry)
+	}
+}
+
+// removeResourceType - lists and deletes the items of one resource type. A type whose API is disabled is skipped
+func (p *pass) removeResourceType(resource Resource, config config.Config) error {
+	defer config.Progress.Done(config.Project, resource.Name())
+	if !serviceEnabled(config, resource.Service()) {
+		skipDisabledService(resource, config)
+		return nil
+	}
+	slog.Debug("Retrieving list of resources", typeLog(resource, config.Project)...)
+	items, err := listResource(resource, config)
+	// Covers projects whose enabled services could not be looked up
+	if classifyError(err) == ErrorServiceDisabled {
+		skipDisabledService(resource, config)
 		return nil
 	}
+	if err != nil {
+		p.mutex.Lock()
+		p.entries = append(p.entries, report.Entry{
+			Type:    resource.Name(),
+			Project: config.Project,
+			Action:  report.Failed,
+			Reason:  "list failed",
+			Error:   err.Error(),
+		})
+		p.mutex.Unlock()
+		return err
+	}
+	p.listed.Store(resource.Name(), items)
 
-	timeOut := config.Timeout
-	pollTime := config.PollTime
-	seconds := 0
+	if config.DryRun {
+		parallelDryRun(resource, config)
+		return nil
+	}
+	err = parallelResourceDeletion(resource, config)
+	remaining := resource.ToSlice()
+	for _, item := range items {
+		if !helpers.SliceContains(remaining, item.FullName) {
+			p.deleted.Add(1)
+		}
+	}
+	return err
+}
 
-	// Wait for dependencies to delete
-	for _, dependencyResourceName := range resource.Dependencies() {
-		if seconds > timeOut {
-			return fmt.Errorf("[Error] Resource %v timed out whilst waiting for dependency %v to delete. (%v seconds)", resource.Name(), dependencyResourceName, timeOut)
+// logRemaining - prints what is still left in a project after the last pass, and why
+func logRemaining(resourceMap map[string]Resource, last *pass, config config.Config) {
+	slog.Warn("Resources remaining", logProject, config.Project, "passes", last.number)
+	// The order was validated before deleting
+	order, _ := dependencyOrder(resourceMap)
+	for _, name := range order {
+		if dependency, found := last.blocked.Load(name); found {
+			slog.Warn("Remaining resource type was not started", logProject, config.Project, logType, name, "dependency", dependency)
+			continue
+		}
+		value, found := last.failures.Load(name)
+		if !found {
+			continue
 		}
-		dependencyResource := resourceMap[dependencyResourceName]
-		for len(dependencyResource.List(false)) != 0 {
-			refreshCache = true
-			time.Sleep(time.Duration(pollTime) * time.Second)
-			seconds += pollTime
-			log.Printf("[Waiting] Resource %v waiting for dependency %v to delete. (%v seconds)\n", resource.Name(), dependencyResource.Name(), seconds)
+		var itemErrors ItemErrors
+		if !errors.As(value.(error), &itemErrors) {
+			slog.Warn("Remaining resource type failed", logProject, config.Project, logType, name, "items", resourceMap[name].ToSlice(), logError, value)
+			continue
+		}
+		for _, itemError := range itemErrors {
+			slog.Warn("Remaining resource", append(itemLog(itemError.Item), "reason", classifyError(itemError.Err).String(), logError, itemError.Err)...)
 		}
 	}
-
-	if refreshCache {
-		resource.List(refreshCache)
+	if last.stopped.Load() {
+		slog.Warn("Resource types not started after the first failure were left as well, see --continue-on-error", logProject, config.Project)
 	}
+}
 
-	log.Println("[Remove] Removing", resource.Name(), "items:", resource.List(false))
-	seconds = 0
-	err := resource.Remove()
+// maxListAttempts - how often listing a type is tried while it is rate limited or temporarily unavailable
+const maxListAttempts = 4
 
-	// Unfortunately the API seems inconsistent with timings, so retry until any dependent resources delete
-	for apiErrorCheck(err) {
-		resource.List(true)
+// listResource - refreshes the items of a resource type, retrying transient failures with backoff
+func listResource(resource Resource, config config.Config) ([]Item, error) {
+	return retryRateLimited(config.Context, config, maxListAttempts, "Listing "+resource.Name(), func() ([]Item, error) {
+		return resource.List(config.Context, true)
+	})
+}
 
-		if seconds > timeOut {
-			return fmt.Errorf("[Error] Resource %v timed out whilst trying to delete. (%v seconds). Details of error below:\n %v", resource.Name(), timeOut, err.Error())
+// retryRateLimited - calls call until it is no longer rate limited or temporarily unavailable, or has been tried attempts times. Waits as long as the API asked for with
+// Retry-After, or otherwise backs off exponentially from the poll time
+func retryRateLimited[T any](ctx context.Context, config config.Config, attempts int, description string, call func() (T, error)) (T, error) {
+	backoff := max(time.Duration(config.PollTime)*time.Second, time.Second)
+	for attempt := 1; ; attempt++ {
+		result, err := call()
+		class := classifyError(err)
+		if !class.backedOff() || attempt == attempts {
+			return result, err
 		}
-
-		log.Printf("[Remove] In use Resource: %v. Items: %v. Waiting before retrying delete. (%v seconds)", resource.Name(), resource.List(false), seconds)
-		time.Sleep(time.Duration(pollTime) * time.Second)
-		seconds += pollTime
-		err = resource.Remove()
+		wait := retryDelay(err)
+		if wait == 0 {
+			wait = backoff
+		}
+		slog.Info(description+" failed temporarily, retrying", logProject, config.Project, "reason", class.String(), "wait", wait.String(), logError, err)
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		case <-time.After(wait):
+		}
+		backoff = min(backoff*2, maxRateLimitBackoff)
 	}
+}
 
-	// Add some info to the error
-	if err != nil {
-		detailedError := fmt.Errorf("[Error] Resource: %v. Items: %v. Details of error below:\n %v", resource.Name(), resource.List(false), err.Error())
-		err = detailedError
+// interrupted - reports whether the run was asked to stop starting new deletions
+func interrupted(config config.Config) bool {
+	select {
+	case <-drained(config):
+		return true
+	default:
+		return false
 	}
+}
 
-	return err
+// drained - closed once the run is asked to stop starting new deletions. Without a Drain that is when Context is done, as documented on config.Config
+func drained(config config.Config) <-chan struct{} {
+	if config.Drain != nil {
+		return config.Drain.Done()
+	}
+	if config.Context != nil {
+		return config.Context.Done()
+	}
+	return nil
 }
 
-// apiErrorCheck - Not proud of this workaround for the inconsistent api timings, suggestions welcome
-func apiErrorCheck(err error) bool {
-	if err == nil {
-		return false
+// logInterrupted - prints what an interrupted run did and did not delete per type, and reports the items it never got to
+func logInterrupted(resourceMap map[string]Resource, listed *sync.Map, config config.Config) {
+	slog.Warn("Deletion interrupted", logProject, config.Project, "dry_run", config.DryRun)
+	// The order was validated before deleting
+	order, _ := dependencyOrder(resourceMap)
+	for _, name := range order {
+		value, started := listed.Load(name)
+		if !started {
+			slog.Warn("Interrupted, resource type was not started", logProject, config.Project, logType, name)
+			continue
+		}
+		remaining := resourceMap[name].ToSlice()
+		deleted := []string{}
+		for _, item := range value.([]Item) {
+			if !helpers.SliceContains(remaining, item.FullName) {
+				deleted = append(deleted, item.FullName)
+			}
+		}
+		slog.Warn("Interrupted resource type", logProject, config.Project, logType, name, "deleted", deleted, "not_deleted", remaining)
+		items, _ := resourceMap[name].List(config.Context, false)
+		for _, item := range items {
+			// Items whose deletion ran keep their deleted or failed entry
+			config.Report.AddIfAbsent(report.Entry{
+				Type:     item.Type,
+				Name:     item.Name,
+				Project:  item.Project,
+				Location: item.Location,
+				Action:   report.Skipped,
+				Reason:   "interrupted",
+			})
+		}
 	}
-	errorDescriptors := []string{
-		"resourceInUseByAnotherResource",
-		"resourceNotReady",
-		// Interestingly in the case of instancegroups managed by GKE, listing them after deletion can often give back a ghost list
-		"googleapi: Error 404",
+}
+
+// maxRateLimitBackoff - upper bound for the wait between retries of rate limited deletions
+const maxRateLimitBackoff = 2 * time.Minute
+
+func parallelResourceDeletion(resource Resource, config config.Config) error {
+	if len(resource.ToSlice()) == 0 {
+		slog.Info("No items to delete", typeLog(resource, config.Project)...)
+		return nil
 	}
-	for _, errorDesc := range errorDescriptors {
-		if strings.Contains(err.Error(), errorDesc) {
-			return true
+
+	timeOut := config.Timeout
+	pollTime := config.PollTime
+	seconds := 0
+
+	slog.Info("Removing resources", append(typeLog(resource, config.Project), "items", resource.ToSlice())...)
+	err := resource.Remove(config.Context)
+	backoff := time.Duration(pollTime) * time.Second
+
+	// Retry for as long as the error class allows it, within the timeout
+	for {
+		class := classifyError(err)
+		var wait time.Duration
+		switch class {
+		case ErrorNone, ErrorNotFound:
+			return nil
+		case ErrorInUse:
+			// Unfortunately the API seems inconsistent with timings, so retry until any dependent resources delete
+			wait = time.Duration(pollTime) * time.Second
+		case ErrorRateLimited, ErrorTransient:
+			wait = backoff
+			backoff = min(backoff*2, maxRateLimitBackoff)
+		case ErrorPermissionDenied:
+			return fmt.Errorf("permission denied removing %v items %v: %w", resource.Name(), resource.ToSlice(), err)
+		default:
+			return fmt.Errorf("removing %v items %v: %w", resource.Name(), resource.ToSlice(), err)
+		}
+
+		if seconds > timeOut {
+			return fmt.Errorf("%v timed out whilst trying to delete after %v seconds: %w", resource.Name(), timeOut, err)
+		}
+
+		slog.Info("Retrying delete", append(typeLog(resource, config.Project), "reason", class.String(), "items", resource.ToSlice(), "wait", wait.String(), logElapsed, seconds)...)
+		// A retry starts new deletions, so it is dropped once the run is interrupted
+		select {
+		case <-drained(config):
+			return fmt.Errorf("interrupted, %v not retried for items %v: %w", resource.Name(), resource.ToSlice(), err)
+		case <-time.After(wait):
+		}
+		seconds += int(wait.Seconds())
+		// A failed refresh is classified like a failed delete
+		if _, err = resource.List(config.Context, true); err == nil {
+			err = resource.Remove(config.Context)
 		}
 	}
-	return false
 }
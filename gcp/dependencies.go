@@ -0,0 +1,69 @@
+package gcp
+
+import (
+	"fmt"
+	"sort"
+)
+
+// dependencyWaves - groups resource type names into waves, where every type only depends on types in earlier waves.
+// Unknown dependencies and cycles are rejected
+func dependencyWaves(resources map[string]Resource) ([][]string, error) {
+	remaining := map[string][]string{}
+	for name, resource := range resources {
+		for _, dependency := range resource.Dependencies() {
+			if _, exists := resources[dependency]; !exists {
+				return nil, fmt.Errorf("resource %v depends on unknown resource %v", name, dependency)
+			}
+		}
+		remaining[name] = resource.Dependencies()
+	}
+
+	waves := [][]string{}
+	done := map[string]bool{}
+	for len(remaining) > 0 {
+		wave := []string{}
+		for name, dependencies := range remaining {
+			satisfied := true
+			for _, dependency := range dependencies {
+				if !done[dependency] {
+					satisfied = false
+				}
+			}
+			if satisfied {
+				wave = append(wave, name)
+			}
+		}
+		if len(wave) == 0 {
+			return nil, fmt.Errorf("dependency cycle between resources %v", sortedKeys(remaining))
+		}
+		sort.Strings(wave)
+		for _, name := range wave {
+			done[name] = true
+			delete(remaining, name)
+		}
+		waves = append(waves, wave)
+	}
+	return waves, nil
+}
+
+// dependencyOrder - resource type names ordered so every type comes after the types it depends on
+func dependencyOrder(resources map[string]Resource) ([]string, error) {
+	waves, err := dependencyWaves(resources)
+	if err != nil {
+		return nil, err
+	}
+	order := []string{}
+	for _, wave := range waves {
+		order = append(order, wave...)
+	}
+	return order, nil
+}
+
+func sortedKeys(input map[string][]string) []string {
+	keys := []string{}
+	for key := range input {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
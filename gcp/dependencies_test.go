@@ -0,0 +1,100 @@
+package gcp
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeResource - a resource type that only has a name and dependencies
+type fakeResource struct {
+	Resource
+	name         string
+	dependencies []string
+}
+
+func (r fakeResource) Name() string {
+	return r.name
+}
+
+func (r fakeResource) Dependencies() []string {
+	return r.dependencies
+}
+
+// fakeResources - resource types keyed by name, each with the given dependencies
+func fakeResources(dependencies map[string][]string) map[string]Resource {
+	resources := map[string]Resource{}
+	for name, needs := range dependencies {
+		resources[name] = fakeResource{name: name, dependencies: needs}
+	}
+	return resources
+}
+
+func TestDependencyWaves(t *testing.T) {
+	tests := []struct {
+		name         string
+		dependencies map[string][]string
+		want         [][]string
+	}{
+		{"empty", map[string][]string{}, [][]string{}},
+		{"independent types share a wave, sorted", map[string][]string{"B": nil, "A": nil}, [][]string{{"A", "B"}}},
+		{"chain", map[string][]string{"Network": {"Subnet"}, "Subnet": {"Instance"}, "Instance": nil}, [][]string{{"Instance"}, {"Subnet"}, {"Network"}}},
+		{"diamond", map[string][]string{"D": {"B", "C"}, "B": {"A"}, "C": {"A"}, "A": nil}, [][]string{{"A"}, {"B", "C"}, {"D"}}},
+		{"waits for the slowest dependency", map[string][]string{"C": {"A", "B"}, "B": {"A"}, "A": nil}, [][]string{{"A"}, {"B"}, {"C"}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			waves, err := dependencyWaves(fakeResources(test.dependencies))
+			if err != nil {
+				t.Fatalf("dependencyWaves: %v", err)
+			}
+			if !reflect.DeepEqual(waves, test.want) {
+				t.Errorf("dependencyWaves = %v, want %v", waves, test.want)
+			}
+		})
+	}
+}
+
+func TestDependencyWavesInvalid(t *testing.T) {
+	tests := []struct {
+		name         string
+		dependencies map[string][]string
+		message      string
+	}{
+		{"unknown dependency", map[string][]string{"A": {"Missing"}}, "unknown resource Missing"},
+		{"self dependency", map[string][]string{"A": {"A"}}, "dependency cycle"},
+		{"cycle", map[string][]string{"A": {"B"}, "B": {"A"}}, "dependency cycle between resources [A B]"},
+		{"cycle behind a valid type", map[string][]string{"Root": nil, "A": {"Root", "B"}, "B": {"A"}}, "dependency cycle between resources [A B]"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := dependencyWaves(fakeResources(test.dependencies))
+			if err == nil || !strings.Contains(err.Error(), test.message) {
+				t.Errorf("dependencyWaves error = %v, want one containing %q", err, test.message)
+			}
+			if _, err := dependencyOrder(fakeResources(test.dependencies)); err == nil {
+				t.Errorf("dependencyOrder succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestDependencyOrder(t *testing.T) {
+	order, err := dependencyOrder(fakeResources(map[string][]string{"D": {"B", "C"}, "B": {"A"}, "C": {"A"}, "A": nil}))
+	if err != nil {
+		t.Fatalf("dependencyOrder: %v", err)
+	}
+	if want := []string{"A", "B", "C", "D"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("dependencyOrder = %v, want %v", order, want)
+	}
+}
+
+func TestDefaultRegistryDependencies(t *testing.T) {
+	registry, err := NewDefaultRegistry()
+	if err != nil {
+		t.Fatalf("NewDefaultRegistry: %v", err)
+	}
+	if err := registry.Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+}
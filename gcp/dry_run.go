@@ -1,16 +1,27 @@
 package gcp
 
 import (
-	"log"
+	"log/slog"
 
 	"github.com/BESTSELLER/gcp-nuke/config"
+	"github.com/BESTSELLER/gcp-nuke/report"
 )
 
 func parallelDryRun(resource Resource, config config.Config) {
-	resourceList := resource.List(false)
+	resourceList := resource.ToSlice()
 	if len(resourceList) == 0 {
-		log.Printf("[Dryrun] [Skip] Resource type %v has nothing to destroy [project: %v]", resource.Name(), config.Project)
+		slog.Info("Dryrun: resource type has nothing to destroy", typeLog(resource, config.Project)...)
 		return
 	}
-	log.Printf("[Dryrun] Resource type %v with resources %v would be destroyed [project: %v]", resource.Name(), resourceList, config.Project)
+	slog.Info("Dryrun: resources would be destroyed", append(typeLog(resource, config.Project), "items", resourceList)...)
+	items, _ := resource.List(config.Context, false)
+	for _, item := range items {
+		config.Report.Add(report.Entry{
+			Type:     item.Type,
+			Name:     item.Name,
+			Project:  item.Project,
+			Location: item.Location,
+			Action:   report.WouldDelete,
+		})
+	}
 }
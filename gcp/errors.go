@@ -0,0 +1,241 @@
+package gcp
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorClass - how the engine treats an error returned by a Google API
+type ErrorClass int
+
+const (
+	// ErrorNone - no error
+	ErrorNone ErrorClass = iota
+	// ErrorInUse - the resource is still referenced or not ready, retried every poll interval
+	ErrorInUse
+	// ErrorNotFound - the resource is already gone, counted as deleted
+	ErrorNotFound
+	// ErrorPermissionDenied - the caller may not delete the resource, never retried
+	ErrorPermissionDenied
+	// ErrorRateLimited - a quota or rate limit was hit, retried with backoff
+	ErrorRateLimited
+	// ErrorTransient - a server error or a temporarily unavailable backend, retried with backoff like ErrorRateLimited
+	ErrorTransient
+	// ErrorServiceDisabled - the API is not enabled in the project, the resource type is skipped
+	ErrorServiceDisabled
+	// ErrorFatal - anything else, never retried
+	ErrorFatal
+)
+
+func (c ErrorClass) String() string {
+	switch c {
+	case ErrorNone:
+		return "none"
+	case ErrorInUse:
+		return "in use"
+	case ErrorNotFound:
+		return "not found"
+	case ErrorPermissionDenied:
+		return "permission denied"
+	case ErrorRateLimited:
+		return "rate limited"
+	case ErrorTransient:
+		return "temporarily unavailable"
+	case ErrorServiceDisabled:
+		return "service disabled"
+	}
+	return "fatal"
+}
+
+// backedOff - reports whether errors of this class are retried with exponential backoff, as the call itself may succeed later
+func (c ErrorClass) backedOff() bool {
+	return c == ErrorRateLimited || c == ErrorTransient
+}
+
+// rateLimitReasons - error reasons that mean a quota or rate limit was hit, these come with 403 as well as 429
+var rateLimitReasons = []string{
+	"rateLimitExceeded",
+	"userRateLimitExceeded",
+	"quotaExceeded",
+	"dailyLimitExceeded",
+}
+
+// inUseReasons - error reasons that mean the resource cannot be deleted yet
+var inUseReasons = []string{
+	"resourceInUseByAnotherResource",
+	"resourceNotReady",
+}
+
+// serviceDisabledReasons - error reasons that mean the API is not enabled in the project, these come with 403
+var serviceDisabledReasons = []string{
+	"accessNotConfigured",
+	"SERVICE_DISABLED",
+}
+
+// classifyError - sorts an error from a REST or gRPC Google API client into an ErrorClass
+func classifyError(err error) ErrorClass {
+	if err == nil {
+		return ErrorNone
+	}
+
+	// Checked first, as the API errors of its items would otherwise be found one by one
+	var itemErrors ItemErrors
+	if errors.As(err, &itemErrors) {
+		return itemErrors.class()
+	}
+
+	var apiError *googleapi.Error
+	if errors.As(err, &apiError) {
+		return classifyAPIError(apiError)
+	}
+
+	var operationError *OperationError
+	if errors.As(err, &operationError) {
+		return classifyOperationError(operationError)
+	}
+
+	if grpcStatus, ok := status.FromError(err); ok {
+		return classifyGRPCCode(grpcStatus.Code())
+	}
+
+	return ErrorFatal
+}
+
+func classifyAPIError(apiError *googleapi.Error) ErrorClass {
+	if serviceDisabled(apiError) {
+		return ErrorServiceDisabled
+	}
+	for _, item := range apiError.Errors {
+		for _, reason := range rateLimitReasons {
+			if item.Reason == reason {
+				return ErrorRateLimited
+			}
+		}
+		for _, reason := range inUseReasons {
+			if item.Reason == reason {
+				return ErrorInUse
+			}
+		}
+	}
+
+	switch apiError.Code {
+	case http.StatusNotFound, http.StatusGone:
+		return ErrorNotFound
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrorPermissionDenied
+	case http.StatusTooManyRequests:
+		return ErrorRateLimited
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return ErrorTransient
+	case http.StatusConflict, http.StatusPreconditionFailed:
+		return ErrorInUse
+	}
+	return ErrorFatal
+}
+
+// serviceDisabled - newer APIs carry the reason in an ErrorInfo detail, older ones in the error items
+func serviceDisabled(apiError *googleapi.Error) bool {
+	reasons := []string{}
+	for _, item := range apiError.Errors {
+		reasons = append(reasons, item.Reason)
+	}
+	for _, detail := range apiError.Details {
+		if info, ok := detail.(map[string]interface{}); ok {
+			if reason, ok := info["reason"].(string); ok {
+				reasons = append(reasons, reason)
+			}
+		}
+	}
+	for _, reason := range reasons {
+		if slices.Contains(serviceDisabledReasons, reason) {
+			return true
+		}
+	}
+	return false
+}
+
+// retryInfoType - type of the error detail in which newer REST APIs say how long to back off
+const retryInfoType = "type.googleapis.com/google.rpc.RetryInfo"
+
+// retryDelay - how long the API asked to wait before retrying, from a Retry-After header or a RetryInfo detail. Zero when it did not say
+func retryDelay(err error) time.Duration {
+	var apiError *googleapi.Error
+	if errors.As(err, &apiError) {
+		if retryAfter := apiError.Header.Get("Retry-After"); retryAfter != "" {
+			if seconds, err := strconv.Atoi(retryAfter); err == nil {
+				return time.Duration(seconds) * time.Second
+			}
+			if at, err := http.ParseTime(retryAfter); err == nil {
+				return max(time.Until(at), 0)
+			}
+		}
+		for _, detail := range apiError.Details {
+			if info, ok := detail.(map[string]interface{}); ok && info["@type"] == retryInfoType {
+				if delay, ok := info["retryDelay"].(string); ok {
+					if duration, err := time.ParseDuration(delay); err == nil {
+						return duration
+					}
+				}
+			}
+		}
+		return 0
+	}
+
+	if grpcStatus, ok := status.FromError(err); ok {
+		for _, detail := range grpcStatus.Details() {
+			if info, ok := detail.(*errdetails.RetryInfo); ok {
+				return info.GetRetryDelay().AsDuration()
+			}
+		}
+	}
+	return 0
+}
+
+// operationErrorCodes - error codes reported by failed compute operations
+var operationErrorCodes = map[string]ErrorClass{
+	"RESOURCE_IN_USE_BY_ANOTHER_RESOURCE": ErrorInUse,
+	"RESOURCE_NOT_READY":                  ErrorInUse,
+	"RESOURCE_NOT_FOUND":                  ErrorNotFound,
+	"QUOTA_EXCEEDED":                      ErrorRateLimited,
+	"RATE_LIMIT_EXCEEDED":                 ErrorRateLimited,
+	"PERMISSIONS_ERROR":                   ErrorPermissionDenied,
+}
+
+func classifyOperationError(operationError *OperationError) ErrorClass {
+	for _, code := range operationError.Codes {
+		if class, known := operationErrorCodes[code]; known {
+			return class
+		}
+		// GKE operations report gRPC codes
+		if number, err := strconv.Atoi(code); err == nil {
+			return classifyGRPCCode(codes.Code(number))
+		}
+	}
+	return ErrorFatal
+}
+
+func classifyGRPCCode(code codes.Code) ErrorClass {
+	switch code {
+	case codes.OK:
+		return ErrorNone
+	case codes.NotFound:
+		return ErrorNotFound
+	case codes.PermissionDenied, codes.Unauthenticated:
+		return ErrorPermissionDenied
+	case codes.ResourceExhausted:
+		return ErrorRateLimited
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return ErrorTransient
+	case codes.FailedPrecondition, codes.Aborted:
+		return ErrorInUse
+	}
+	return ErrorFatal
+}
@@ -0,0 +1,121 @@
+package gcp
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// apiError - a REST error with the given code and reasons in its error items
+func apiError(code int, reasons ...string) *googleapi.Error {
+	err := &googleapi.Error{Code: code}
+	for _, reason := range reasons {
+		err.Errors = append(err.Errors, googleapi.ErrorItem{Reason: reason})
+	}
+	return err
+}
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ErrorClass
+	}{
+		{"nil", nil, ErrorNone},
+		{"plain error", errors.New("boom"), ErrorFatal},
+
+		{"api not found", apiError(http.StatusNotFound), ErrorNotFound},
+		{"api gone", apiError(http.StatusGone), ErrorNotFound},
+		{"api forbidden", apiError(http.StatusForbidden), ErrorPermissionDenied},
+		{"api unauthorized", apiError(http.StatusUnauthorized), ErrorPermissionDenied},
+		{"api too many requests", apiError(http.StatusTooManyRequests), ErrorRateLimited},
+		{"api internal error", apiError(http.StatusInternalServerError), ErrorTransient},
+		{"api bad gateway", apiError(http.StatusBadGateway), ErrorTransient},
+		{"api unavailable", apiError(http.StatusServiceUnavailable), ErrorTransient},
+		{"api gateway timeout", apiError(http.StatusGatewayTimeout), ErrorTransient},
+		{"api rate limit reason on 503", apiError(http.StatusServiceUnavailable, "rateLimitExceeded"), ErrorRateLimited},
+		{"api conflict", apiError(http.StatusConflict), ErrorInUse},
+		{"api bad request", apiError(http.StatusBadRequest), ErrorFatal},
+		{"api rate limit reason on 403", apiError(http.StatusForbidden, "rateLimitExceeded"), ErrorRateLimited},
+		{"api quota reason on 403", apiError(http.StatusForbidden, "quotaExceeded"), ErrorRateLimited},
+		{"api in use reason on 400", apiError(http.StatusBadRequest, "resourceInUseByAnotherResource"), ErrorInUse},
+		{"api not ready reason", apiError(http.StatusBadRequest, "resourceNotReady"), ErrorInUse},
+		{"api service disabled reason", apiError(http.StatusForbidden, "accessNotConfigured"), ErrorServiceDisabled},
+		{"api service disabled detail", &googleapi.Error{Code: http.StatusForbidden, Details: []interface{}{map[string]interface{}{"reason": "SERVICE_DISABLED"}}}, ErrorServiceDisabled},
+		{"wrapped api error", fmt.Errorf("List: %w", apiError(http.StatusNotFound)), ErrorNotFound},
+
+		{"grpc not found", status.Error(codes.NotFound, ""), ErrorNotFound},
+		{"grpc permission denied", status.Error(codes.PermissionDenied, ""), ErrorPermissionDenied},
+		{"grpc unauthenticated", status.Error(codes.Unauthenticated, ""), ErrorPermissionDenied},
+		{"grpc resource exhausted", status.Error(codes.ResourceExhausted, ""), ErrorRateLimited},
+		{"grpc unavailable", status.Error(codes.Unavailable, ""), ErrorTransient},
+		{"grpc deadline exceeded", status.Error(codes.DeadlineExceeded, ""), ErrorTransient},
+		{"grpc failed precondition", status.Error(codes.FailedPrecondition, ""), ErrorInUse},
+		{"grpc internal", status.Error(codes.Internal, ""), ErrorFatal},
+
+		{"operation in use", &OperationError{Codes: []string{"RESOURCE_IN_USE_BY_ANOTHER_RESOURCE"}}, ErrorInUse},
+		{"operation not found", &OperationError{Codes: []string{"RESOURCE_NOT_FOUND"}}, ErrorNotFound},
+		{"operation quota", &OperationError{Codes: []string{"QUOTA_EXCEEDED"}}, ErrorRateLimited},
+		{"operation permissions", &OperationError{Codes: []string{"PERMISSIONS_ERROR"}}, ErrorPermissionDenied},
+		{"operation unknown code", &OperationError{Codes: []string{"SOMETHING_ELSE"}}, ErrorFatal},
+		{"operation first known code wins", &OperationError{Codes: []string{"SOMETHING_ELSE", "RESOURCE_NOT_READY"}}, ErrorInUse},
+		{"gke operation grpc code", &OperationError{Codes: []string{fmt.Sprint(int(codes.FailedPrecondition))}}, ErrorInUse},
+		{"wrapped operation error", fmt.Errorf("wait: %w", &OperationError{Codes: []string{"RESOURCE_NOT_FOUND"}}), ErrorNotFound},
+
+		{"items all gone", ItemErrors{{Err: apiError(http.StatusNotFound)}}, ErrorNotFound},
+		{"items in use before fatal", ItemErrors{{Err: errors.New("boom")}, {Err: apiError(http.StatusConflict)}}, ErrorInUse},
+		{"items transient before in use", ItemErrors{{Err: apiError(http.StatusConflict)}, {Err: apiError(http.StatusBadGateway)}}, ErrorTransient},
+		{"items rate limited before in use", ItemErrors{{Err: apiError(http.StatusConflict)}, {Err: apiError(http.StatusTooManyRequests)}}, ErrorRateLimited},
+		{"items first final failure", ItemErrors{{Err: apiError(http.StatusNotFound)}, {Err: apiError(http.StatusForbidden)}, {Err: errors.New("boom")}}, ErrorPermissionDenied},
+		{"items wrapped with item api errors", fmt.Errorf("removing: %w", ItemErrors{{Err: apiError(http.StatusForbidden)}, {Err: apiError(http.StatusConflict)}}), ErrorInUse},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := classifyError(test.err); got != test.want {
+				t.Errorf("classifyError(%v) = %v, want %v", test.err, got, test.want)
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	withHeader := apiError(http.StatusTooManyRequests)
+	withHeader.Header = http.Header{"Retry-After": []string{"7"}}
+
+	withDetail := apiError(http.StatusTooManyRequests)
+	withDetail.Details = []interface{}{map[string]interface{}{"@type": retryInfoType, "retryDelay": "2.5s"}}
+
+	grpcStatus, err := status.New(codes.ResourceExhausted, "slow down").WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(3 * time.Second)})
+	if err != nil {
+		t.Fatalf("WithDetails: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want time.Duration
+	}{
+		{"nil", nil, 0},
+		{"no hint", apiError(http.StatusTooManyRequests), 0},
+		{"retry after seconds", withHeader, 7 * time.Second},
+		{"retry info detail", withDetail, 2500 * time.Millisecond},
+		{"wrapped", fmt.Errorf("delete: %w", withHeader), 7 * time.Second},
+		{"grpc retry info", grpcStatus.Err(), 3 * time.Second},
+		{"grpc without retry info", status.Error(codes.ResourceExhausted, ""), 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := retryDelay(test.err); got != test.want {
+				t.Errorf("retryDelay(%v) = %v, want %v", test.err, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package gcp
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// ItemError - why a single item could not be deleted
+type ItemError struct {
+	Item Item
+	Err  error
+}
+
+func (e *ItemError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Item.FullName, e.Err)
+}
+
+func (e *ItemError) Unwrap() error {
+	return e.Err
+}
+
+// ItemErrors - every item of a resource type that could not be deleted, sorted by full name
+type ItemErrors []*ItemError
+
+func (e ItemErrors) Error() string {
+	messages := []string{}
+	for _, itemError := range e {
+		messages = append(messages, itemError.Error())
+	}
+	return fmt.Sprintf("%v item(s) failed: %v", len(e), strings.Join(messages, "; "))
+}
+
+func (e ItemErrors) Unwrap() []error {
+	errs := []error{}
+	for _, itemError := range e {
+		errs = append(errs, itemError)
+	}
+	return errs
+}
+
+// class - the deletion is retried while any item may still succeed, rate limited and temporarily unavailable items first as they need the longer backoff.
+// Otherwise the class of the first item that failed for good
+func (e ItemErrors) class() ErrorClass {
+	classes := []ErrorClass{}
+	for _, itemError := range e {
+		classes = append(classes, classifyError(itemError.Err))
+	}
+	for _, retried := range []ErrorClass{ErrorRateLimited, ErrorTransient, ErrorInUse} {
+		for _, class := range classes {
+			if class == retried {
+				return class
+			}
+		}
+	}
+	for _, class := range classes {
+		if class != ErrorNone && class != ErrorNotFound {
+			return class
+		}
+	}
+	return ErrorNotFound
+}
+
+// TypeError - why a resource type failed, wrapping ItemErrors when single items could not be deleted
+type TypeError struct {
+	Type string
+	Err  error
+}
+
+func (e *TypeError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Type, e.Err)
+}
+
+func (e *TypeError) Unwrap() error {
+	return e.Err
+}
+
+// FailedTypes - the resource types of a project that failed, in dependency order
+type FailedTypes struct {
+	// Total - number of resource types in the project
+	Total  int
+	Errors []*TypeError
+}
+
+func (e *FailedTypes) Error() string {
+	names := []string{}
+	for _, typeError := range e.Errors {
+		names = append(names, typeError.Type)
+	}
+	return fmt.Sprintf("%v of %v resource types failed: %v", len(e.Errors), e.Total, names)
+}
+
+func (e *FailedTypes) Unwrap() []error {
+	errs := []error{}
+	for _, typeError := range e.Errors {
+		errs = append(errs, typeError)
+	}
+	return errs
+}
+
+// LogFailureSummary - prints every failure of the run grouped by project and resource type, down to the single items
+func LogFailureSummary(results []ProjectResult) {
+	slog.Info("-- Failures --")
+	for _, result := range results {
+		if result.Err == nil {
+			continue
+		}
+		var failedTypes *FailedTypes
+		if !errors.As(result.Err, &failedTypes) {
+			slog.Error("Project failed", logProject, result.Project, logError, result.Err)
+			continue
+		}
+		for _, typeError := range failedTypes.Errors {
+			var itemErrors ItemErrors
+			if !errors.As(typeError.Err, &itemErrors) {
+				slog.Error("Resource type failed", logProject, result.Project, logType, typeError.Type, logError, typeError.Err)
+				continue
+			}
+			slog.Error("Resource type failed", logProject, result.Project, logType, typeError.Type, "failed_items", len(itemErrors))
+			for _, itemError := range itemErrors {
+				slog.Error("Resource not deleted", append(itemLog(itemError.Item), logError, itemError.Err)...)
+			}
+		}
+	}
+}
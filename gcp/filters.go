@@ -0,0 +1,111 @@
+package gcp
+
+import (
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/BESTSELLER/gcp-nuke/config"
+	"github.com/BESTSELLER/gcp-nuke/report"
+)
+
+// admit - runs a listed item through the label, age, name and plan filters, in that order, and tracks it when all of them keep it
+func (b *ResourceBase) admit(item Item, filters FilterSupport, patterns config.NamePatterns) {
+	if b.labelFiltered(item, filters.Labels) {
+		return
+	}
+	if b.ageFiltered(item) || b.excluded(item, patterns, filters.ParentNames) || b.planFiltered(item) {
+		return
+	}
+	b.track(item)
+}
+
+// labelFiltered - reports whether the configured label selectors keep this item, logging when they do.
+// Items of types without labels are always kept while include selectors are set, as they can never match them
+func (b *ResourceBase) labelFiltered(item Item, supported bool) bool {
+	if !supported {
+		if len(b.config.Exclusions.Labels.Include) == 0 {
+			return false
+		}
+		slog.Info("Excluded resource", append(itemLog(item), "reason", "labels unsupported")...)
+		b.record(item, report.Excluded, "labels unsupported")
+		return true
+	}
+	if b.config.Exclusions.Labels.Allows(item.Labels) {
+		return false
+	}
+	slog.Info("Excluded resource", append(itemLog(item), "reason", "labels", "labels", item.Labels)...)
+	b.record(item, report.Excluded, "labels")
+	return true
+}
+
+// excluded - reports whether the short or full name of the item, or of one of its parents when parentNames is set, matches the exclusion patterns for its type,
+// logging when it does
+func (b *ResourceBase) excluded(item Item, patterns config.NamePatterns, parentNames bool) bool {
+	names := []string{item.Name, item.FullName}
+	if parentNames {
+		for _, parent := range item.Parents {
+			names = append(names, lastSegment(parent), parent)
+		}
+	}
+	if !slices.ContainsFunc(names, patterns.Matches) {
+		return false
+	}
+	slog.Info("Excluded resource", append(itemLog(item), "reason", "name")...)
+	b.record(item, report.Excluded, "name")
+	return true
+}
+
+// ageFiltered - reports whether the configured age filter keeps this item, logging when it does. Items of unknown age are always kept while a filter is set
+func (b *ResourceBase) ageFiltered(item Item) bool {
+	filter := b.config.Exclusions.Age.For(item.Type)
+	if filter.IsZero() {
+		return false
+	}
+	if item.CreatedAt.IsZero() {
+		slog.Info("Excluded resource", append(itemLog(item), "reason", "unknown age")...)
+		b.record(item, report.Excluded, "unknown age")
+		return true
+	}
+	if filter.Allows(item.CreatedAt, time.Now()) {
+		return false
+	}
+	slog.Info("Excluded resource", append(itemLog(item), "reason", "age", "created_at", item.CreatedAt.Format(time.RFC3339))...)
+	b.record(item, report.Excluded, "age")
+	return true
+}
+
+// parseTimestamp - parses an RFC 3339 timestamp as returned by the APIs, a zero time means unknown
+func parseTimestamp(timestamp string) time.Time {
+	created, err := time.Parse(time.RFC3339Nano, timestamp)
+	if err != nil {
+		return time.Time{}
+	}
+	return created
+}
+
+// planFiltered - when applying a plan, reports whether this item is kept because it was not planned or has changed since, logging when it is.
+// An item changed when it was recreated, relabeled or otherwise modified
+func (b *ResourceBase) planFiltered(item Item) bool {
+	if b.config.Planned == nil {
+		return false
+	}
+	plannedItems, typePlanned := b.config.Planned[item.Type]
+	if !typePlanned {
+		// Only listed as a dependency of a planned type, or planned for other projects, so it was never in scope
+		slog.Debug("Kept resource, its type is not in the plan", itemLog(item)...)
+		return true
+	}
+	state, planned := plannedItems[item.FullName]
+	if !planned {
+		slog.Warn("Refused resource, it appeared since the plan", itemLog(item)...)
+		b.record(item, report.Skipped, "not in plan")
+		return true
+	}
+	if state != item.state() {
+		slog.Warn("Refused resource, it changed since the plan", itemLog(item)...)
+		b.record(item, report.Skipped, "changed since plan")
+		return true
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+package gcp
+
+import (
+	"testing"
+
+	"github.com/BESTSELLER/gcp-nuke/config"
+)
+
+func TestExcluded(t *testing.T) {
+	peering := Item{
+		Type:     "ComputeNetworkPeerings",
+		Name:     "to-shared",
+		FullName: "projects/p/global/networks/vpc/peerings/to-shared",
+		Parents:  []string{"projects/p/global/networks/vpc"},
+	}
+	tests := []struct {
+		name        string
+		patterns    []string
+		parentNames bool
+		want        bool
+	}{
+		{"no patterns", nil, true, false},
+		{"short name", []string{"to-*"}, false, true},
+		{"full name", []string{"re:^projects/p/.*/peerings/to-shared$"}, false, true},
+		{"parent ignored by default", []string{"vpc"}, false, false},
+		{"parent short name", []string{"vpc"}, true, true},
+		{"parent full name", []string{"projects/p/global/networks/*"}, true, true},
+		{"other parent", []string{"default"}, true, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			patterns, err := config.ParseNamePatterns(test.patterns...)
+			if err != nil {
+				t.Fatalf("ParseNamePatterns: %v", err)
+			}
+			b := &ResourceBase{}
+			if got := b.excluded(peering, patterns, test.parentNames); got != test.want {
+				t.Errorf("excluded = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
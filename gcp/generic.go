@@ -0,0 +1,177 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+
+	"github.com/BESTSELLER/gcp-nuke/config"
+)
+
+// definition - what a resource type supplies, genericResource does the rest. C is the API client of the type and O what starting a deletion returns,
+// e.g. *compute.Service and *compute.Operation
+type definition[C, O any] interface {
+	Name() string
+	// Service - API that must be enabled in the project, e.g. compute.googleapis.com
+	Service() string
+	// ConfigKey - key of the name exclusions for this type in the config file, e.g. compute_instance
+	ConfigKey() string
+	Filters() FilterSupport
+	Dependencies() []string
+	// newClient - creates the API client, once per project
+	newClient(ctx context.Context, b *ResourceBase) (C, error)
+	// listPage - lists one page of items and returns the token of the next page, empty after the last page. Type and Project may be left empty
+	listPage(ctx context.Context, b *ResourceBase, client C, pageToken string) ([]Item, string, error)
+	// deleteOne - starts the deletion of a single item
+	deleteOne(ctx context.Context, b *ResourceBase, client C, item Item) (O, error)
+	// waitForOp - waits until a started deletion has finished, returning how long it took in seconds
+	waitForOp(ctx context.Context, b *ResourceBase, client C, item Item, operation O) (int, error)
+}
+
+// listPreparer - implemented by definitions that need to look something up before the first page is listed
+type listPreparer[C any] interface {
+	prepareList(ctx context.Context, b *ResourceBase, client C) error
+}
+
+// genericResource - implements Resource for a definition: filtering, exclusions, parallel deletion, logging and reporting
+type genericResource[C, O any] struct {
+	definition[C, O]
+	base   ResourceBase
+	client C
+}
+
+// newResource - wraps a definition into a Resource
+func newResource[C, O any](definition definition[C, O]) Resource {
+	return &genericResource[C, O]{definition: definition}
+}
+
+// ToSlice - full names of the listed items that are not deleted yet
+func (r *genericResource[C, O]) ToSlice() []string {
+	return r.base.fullNames()
+}
+
+// Setup - populates the struct
+func (r *genericResource[C, O]) Setup(config config.Config) error {
+	r.base.config = config
+
+	client, err := r.newClient(config.Context, &r.base)
+	if err != nil {
+		return fmt.Errorf("%v.Setup: %w", r.Name(), err)
+	}
+	r.client = client
+	return nil
+}
+
+// List - lists every page and keeps the items that pass the filters, or returns the cached items when refreshCache is false
+func (r *genericResource[C, O]) List(ctx context.Context, refreshCache bool) ([]Item, error) {
+	if !refreshCache {
+		return r.base.listed(), nil
+	}
+	// Refresh the listed items
+	r.base.reset()
+
+	if preparer, ok := r.definition.(listPreparer[C]); ok {
+		if err := preparer.prepareList(ctx, &r.base, r.client); err != nil {
+			return nil, fmt.Errorf("%v.List: %w", r.Name(), err)
+		}
+	}
+
+	patterns := r.base.config.Exclusions.Patterns(r.ConfigKey())
+	pageToken := ""
+	// seen - items listed before filtering, those not kept count as excluded in the progress
+	seen := 0
+	for {
+		if err := r.base.config.Limiter.Wait(ctx); err != nil {
+			return nil, fmt.Errorf("%v.List: %w", r.Name(), err)
+		}
+		items, nextPageToken, err := r.listPage(ctx, &r.base, r.client, pageToken)
+		if err != nil {
+			return nil, fmt.Errorf("%v.List: %w", r.Name(), err)
+		}
+		seen += len(items)
+		for _, item := range items {
+			item.Type = r.Name()
+			if item.Project == "" {
+				item.Project = r.base.config.Project
+			}
+			r.base.admit(item, r.Filters(), patterns)
+		}
+		if nextPageToken == "" {
+			listed := r.base.listed()
+			r.base.config.Progress.Listed(r.base.config.Project, r.Name(), len(listed), seen-len(listed))
+			return listed, nil
+		}
+		pageToken = nextPageToken
+	}
+}
+
+// maxDeleteAttempts - how often starting a single deletion is tried while it is rate limited
+const maxDeleteAttempts = 5
+
+// Remove - deletes every listed item in parallel, as far as the limiter allows, and waits for the deletions. Cancelling ctx abandons them.
+// Returns ItemErrors holding every item that could not be deleted
+func (r *genericResource[C, O]) Remove(ctx context.Context) error {
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	failed := ItemErrors{}
+
+	for _, item := range r.base.listed() {
+		deletion := r.base.reported(item, func() error {
+			operation, err := retryRateLimited(ctx, r.base.config, maxDeleteAttempts, "Deleting "+item.FullName, func() (O, error) {
+				if err := r.base.config.Limiter.Wait(ctx); err != nil {
+					var none O
+					return none, err
+				}
+				return r.deleteOne(ctx, &r.base, r.client, item)
+			})
+			if err != nil {
+				return err
+			}
+			seconds, err := r.waitForOp(ctx, &r.base, r.client, item, operation)
+			if err != nil {
+				return err
+			}
+			r.base.forget(item)
+
+			slog.Info("Resource deleted", append(itemLog(item), logElapsed, seconds)...)
+			return nil
+		})
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := r.startDeletion(ctx, deletion)
+			if err != nil {
+				mutex.Lock()
+				failed = append(failed, &ItemError{Item: item, Err: err})
+				mutex.Unlock()
+			}
+		}()
+	}
+	// Wait for all deletions to complete
+	wg.Wait()
+
+	if len(failed) == 0 {
+		return nil
+	}
+	sort.Slice(failed, func(i, j int) bool {
+		return failed[i].Item.FullName < failed[j].Item.FullName
+	})
+	return failed
+}
+
+// startDeletion - runs a deletion once the limiter has a slot for it. Deletions still queued when the run is interrupted are never started
+func (r *genericResource[C, O]) startDeletion(ctx context.Context, deletion func() error) error {
+	release, err := r.base.config.Limiter.Acquire(ctx, r.Service())
+	if err != nil {
+		return err
+	}
+	defer release()
+	if interrupted(r.base.config) {
+		return nil
+	}
+	r.base.config.Progress.Deleting(r.base.config.Project, r.Name())
+	return deletion()
+}
@@ -1,25 +1,14 @@
 package gcp
 
 import (
-	"fmt"
-	"log"
-	"sync"
-	"time"
+	"context"
 
-	"github.com/BESTSELLER/gcp-nuke/config"
-	"github.com/BESTSELLER/gcp-nuke/helpers"
-	"golang.org/x/exp/slices"
-	"golang.org/x/sync/errgroup"
-	"golang.org/x/sync/syncmap"
 	"google.golang.org/api/compute/v1"
-	"google.golang.org/api/option"
 )
 
 // ComputeNetworks -
 type ComputeNetworks struct {
-	serviceClient *compute.Service
-	base          ResourceBase
-	resourceMap   syncmap.Map
+	computeDefinition
 }
 
 // Name - Name of the resourceLister for ComputeNetworks
@@ -27,43 +16,19 @@ func (c *ComputeNetworks) Name() string {
 	return "ComputeNetworks"
 }
 
-// ToSlice - Name of the resourceLister for ComputeNetworks
-func (c *ComputeNetworks) ToSlice() (slice []string) {
-	return helpers.SortedSyncMapKeys(&c.resourceMap)
+// Service - API that must be enabled for ComputeNetworks
+func (c *ComputeNetworks) Service() string {
+	return "compute.googleapis.com"
 }
 
-// Setup - populates the struct
-func (c *ComputeNetworks) Setup(config config.Config) {
-	c.base.config = config
-
-	computeService, err := compute.NewService(Ctx, option.WithTokenSource(config.GCPToken))
-	if err != nil {
-		log.Fatalf("ComputeNetworks.Setup.NewService: %s", err)
-	}
-	computeResource := ComputeNetworks{
-		serviceClient: computeService,
-	}
-	register(&computeResource)
+// ConfigKey - key of the ComputeNetworks exclusions in the config file
+func (c *ComputeNetworks) ConfigKey() string {
+	return "google_compute_network"
 }
 
-// List - Returns a list of all ComputeNetworks
-func (c *ComputeNetworks) List(refreshCache bool) []string {
-	if !refreshCache {
-		return c.ToSlice()
-	}
-	// Refresh resource map
-	c.resourceMap = sync.Map{}
-
-	networkListCall := c.serviceClient.Networks.List(c.base.config.Project)
-	networkList, err := networkListCall.Do()
-	if err != nil {
-		log.Fatalf("ComputeNetworks.List: %s", err)
-	}
-
-	for _, network := range networkList.Items {
-		c.resourceMap.Store(network.Name, nil)
-	}
-	return c.ToSlice()
+// Filters - filters ComputeNetworks items can be selected by
+func (c *ComputeNetworks) Filters() FilterSupport {
+	return FilterSupport{Labels: false, Age: true}
 }
 
 // Dependencies - Returns a List of resource names to check for
@@ -72,54 +37,25 @@ func (c *ComputeNetworks) Dependencies() []string {
 	return []string{a.Name()}
 }
 
-// Remove -
-func (c *ComputeNetworks) Remove() error {
-	// Removal logic
-	errs, _ := errgroup.WithContext(c.base.config.Context)
-
-	c.resourceMap.Range(func(key, value interface{}) bool {
-		networkID := key.(string)
-
-		// Check if a resource is excluded from deletion
-		if slices.Contains(c.base.config.Exclusions.GoogleComputeNetwork, networkID) {
-			log.Printf("[Info] Excluded resource: %v (%v)", networkID, c.Name())
-			// This instanceID is excluded from deletion, returning
-			return false
-		}
-
-		// Parallel network deletion
-		errs.Go(func() error {
-			deleteCall := c.serviceClient.Networks.Delete(c.base.config.Project, networkID)
-			operation, err := deleteCall.Do()
-			if err != nil {
-				return err
-			}
-			var opStatus string
-			seconds := 0
-			for opStatus != "DONE" {
-				log.Printf("[Info] Resource currently being deleted %v [type: %v project: %v] (%v seconds)", networkID, c.Name(), c.base.config.Project, seconds)
-
-				operationCall := c.serviceClient.GlobalOperations.Get(c.base.config.Project, operation.Name)
-				checkOpp, err := operationCall.Do()
-				if err != nil {
-					return err
-				}
-				opStatus = checkOpp.Status
-
-				time.Sleep(time.Duration(c.base.config.PollTime) * time.Second)
-				seconds += c.base.config.PollTime
-				if seconds > c.base.config.Timeout {
-					return fmt.Errorf("[Error] Resource deletion timed out for %v [type: %v project: %v] (%v seconds)", networkID, c.Name(), c.base.config.Project, c.base.config.Timeout)
-				}
-			}
-			c.resourceMap.Delete(networkID)
-
-			log.Printf("[Info] Resource deleted %v [type: %v project: %v] (%v seconds)", networkID, c.Name(), c.base.config.Project, seconds)
-			return nil
+// listPage - lists one page of the networks
+func (c *ComputeNetworks) listPage(ctx context.Context, b *ResourceBase, client *compute.Service, pageToken string) ([]Item, string, error) {
+	page, err := client.Networks.List(b.config.Project).PageToken(pageToken).Context(ctx).Do()
+	if err != nil {
+		return nil, "", err
+	}
+	items := []Item{}
+	for _, network := range page.Items {
+		items = append(items, Item{
+			Name:        network.Name,
+			FullName:    relativeName(network.SelfLink),
+			CreatedAt:   parseTimestamp(network.CreationTimestamp),
+			Fingerprint: computeID(network.Id),
 		})
-		return true
-	})
-	// Wait for all deletions to complete, and return the first non nil error
-	err := errs.Wait()
-	return err
+	}
+	return items, page.NextPageToken, nil
+}
+
+// deleteOne - starts deleting a network
+func (c *ComputeNetworks) deleteOne(ctx context.Context, b *ResourceBase, client *compute.Service, item Item) (*compute.Operation, error) {
+	return client.Networks.Delete(item.Project, item.Name).Context(ctx).Do()
 }
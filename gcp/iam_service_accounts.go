@@ -1,104 +1,64 @@
 package gcp
 
 import (
-	"log"
+	"context"
 	"strings"
-	"sync"
 
-	"github.com/BESTSELLER/gcp-nuke/config"
-	"github.com/BESTSELLER/gcp-nuke/helpers"
-	"golang.org/x/exp/slices"
-	"golang.org/x/sync/errgroup"
-	"golang.org/x/sync/syncmap"
 	"google.golang.org/api/iam/v1"
 	"google.golang.org/api/option"
 )
 
-type IAMServiceAccount struct {
-	serviceClient *iam.Service
-	base          ResourceBase
-	resourceMap   syncmap.Map
-	TopicIDs      []string
-}
+type IAMServiceAccount struct{}
 
 func (c *IAMServiceAccount) Name() string {
 	return "IAMServiceAccount"
 }
 
-func (c *IAMServiceAccount) ToSlice() (slice []string) {
-	return helpers.SortedSyncMapKeys(&c.resourceMap)
+func (c *IAMServiceAccount) Service() string {
+	return "iam.googleapis.com"
 }
 
-func (c *IAMServiceAccount) Setup(config config.Config) {
-	c.base.config = config
+func (c *IAMServiceAccount) ConfigKey() string {
+	return "iam_service_account"
+}
 
-	iamService, err := iam.NewService(Ctx, option.WithTokenSource(config.GCPToken))
-	if err != nil {
-		log.Fatalf("IAMServiceAccount.Setup.NewService: %s", err)
-	}
+func (c *IAMServiceAccount) Filters() FilterSupport {
+	return FilterSupport{Labels: false, Age: false}
+}
 
-	iamResource := IAMServiceAccount{
-		serviceClient: iamService,
-	}
-	register(&iamResource)
+// Dependencies - Returns a List of resource names to check for
+func (c *IAMServiceAccount) Dependencies() []string {
+	return []string{}
 }
 
-func (c *IAMServiceAccount) List(refreshCache bool) []string {
-	if !refreshCache {
-		return c.ToSlice()
-	}
-	// Refresh resource map
-	c.resourceMap = sync.Map{}
+func (c *IAMServiceAccount) newClient(ctx context.Context, b *ResourceBase) (*iam.Service, error) {
+	return iam.NewService(ctx, option.WithTokenSource(b.config.GCPToken))
+}
 
-	serviceAccountList, err := c.serviceClient.Projects.ServiceAccounts.List("projects/" + c.base.config.Project).Do()
+func (c *IAMServiceAccount) listPage(ctx context.Context, b *ResourceBase, client *iam.Service, pageToken string) ([]Item, string, error) {
+	page, err := client.Projects.ServiceAccounts.List("projects/" + b.config.Project).PageToken(pageToken).Context(ctx).Do()
 	if err != nil {
-		log.Fatalf("IAMServiceAccount.List: %s", err)
+		return nil, "", err
 	}
-
-	for _, serviceAccount := range serviceAccountList.Accounts {
+	items := []Item{}
+	for _, serviceAccount := range page.Accounts {
 		// Will not list / delete default service accounts
-		if strings.Contains(serviceAccount.Email, c.base.config.Project) {
-			c.resourceMap.Store(serviceAccount.DisplayName, serviceAccount.Email)
+		if strings.Contains(serviceAccount.Email, b.config.Project) {
+			items = append(items, Item{
+				Name:        serviceAccount.Email,
+				FullName:    serviceAccount.Name,
+				Fingerprint: serviceAccount.UniqueId,
+			})
 		}
 	}
-
-	return c.ToSlice()
+	return items, page.NextPageToken, nil
 }
 
-// Dependencies - Returns a List of resource names to check for
-func (c *IAMServiceAccount) Dependencies() []string {
-	return []string{}
+func (c *IAMServiceAccount) deleteOne(ctx context.Context, b *ResourceBase, client *iam.Service, item Item) (*iam.Empty, error) {
+	return client.Projects.ServiceAccounts.Delete(item.FullName).Context(ctx).Do()
 }
 
-func (c *IAMServiceAccount) Remove() error {
-	// Removal logic
-	errs, _ := errgroup.WithContext(c.base.config.Context)
-
-	c.resourceMap.Range(func(key, value interface{}) bool {
-		emailAddress := value.(string)
-
-		// Check if a resource is exclued from deletion
-		if slices.Contains(c.base.config.Exclusions.IAMServiceAccount, emailAddress) {
-			log.Printf("[Info] Excluded resource: %v (%v)", emailAddress, c.Name())
-			// This instanceID is excluded from deletion, returning
-			return false
-		}
-
-		// Parallel instance deletion
-		errs.Go(func() error {
-			_, err := c.serviceClient.Projects.ServiceAccounts.Delete("projects/" + c.base.config.Project + "/serviceAccounts/" + emailAddress).Do()
-			if err != nil {
-				return err
-			}
-
-			seconds := 0
-
-			log.Printf("[Info] Resource deleted %v [type: %v project: %v] (%v seconds)", emailAddress, c.Name(), c.base.config.Project, seconds)
-			return nil
-		})
-		return true
-	})
-	// Wait for all deletions to complete, and return the first non nil error
-	err := errs.Wait()
-	return err
+// waitForOp - service accounts are deleted immediately
+func (c *IAMServiceAccount) waitForOp(ctx context.Context, b *ResourceBase, client *iam.Service, item Item, _ *iam.Empty) (int, error) {
+	return 0, nil
 }
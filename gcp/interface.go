@@ -2,10 +2,12 @@ package gcp
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"log/slog"
 	"strings"
 
 	"github.com/BESTSELLER/gcp-nuke/config"
+	"golang.org/x/sync/syncmap"
 	"google.golang.org/api/compute/v1"
 	"google.golang.org/api/option"
 )
@@ -13,78 +15,77 @@ import (
 // ResourceBase -
 type ResourceBase struct {
 	config config.Config
+	// items - listed items that are not deleted yet, keyed by full name
+	items syncmap.Map
 }
 
-// DefaultResourceProperties -
-type DefaultResourceProperties struct {
-	project string
-	zone    string
-	region  string
+// FilterSupport - the list time filters a resource type honours
+type FilterSupport struct {
+	// Labels - items carry labels, so label selectors apply
+	Labels bool `json:"labels"`
+	// Age - items carry a creation time, so age filters apply. Without one, items are kept while an age filter is set
+	Age bool `json:"age"`
+	// ParentNames - name exclusions also match the names of the parents, e.g. peerings are excluded by the name of their network
+	ParentNames bool `json:"parent_names"`
 }
 
 // Resource -
 type Resource interface {
 	Name() string
+	// Service - API that must be enabled in the project, e.g. compute.googleapis.com
+	Service() string
+	// ConfigKey - key of the name exclusions for this type in the config file, e.g. compute_instance
+	ConfigKey() string
+	Filters() FilterSupport
 	ToSlice() []string
-	Setup(config config.Config)
-	List(useCache bool) []string
+	Setup(config config.Config) error
+	// List - lists the items to delete, or returns the cached items when refreshCache is false
+	List(ctx context.Context, refreshCache bool) ([]Item, error)
 	Dependencies() []string
-	Remove() error
+	// Remove - deletes every listed item and waits for the deletions, cancelling ctx abandons them
+	Remove(ctx context.Context) error
 }
 
-// Ctx = context
-var (
-	Ctx         = context.Background()
-	resourceMap = make(map[string]Resource)
-)
-
-func register(resource Resource) {
-	_, exists := resourceMap[resource.Name()]
-	if exists {
-		log.Fatalf("a resource with the name %s already exists", resource.Name())
-	}
-	resourceMap[resource.Name()] = resource
-}
-
-// GetResourceMap -
-func GetResourceMap(config config.Config) map[string]Resource {
-	for _, resource := range resourceMap {
-		resource.Setup(config)
-	}
-
-	return resourceMap
-}
-
-func AddZonesToConfig(defaultContext context.Context, project string, config config.Config) {
+// AddZonesToConfig - populates the zones available to the configured project
+func AddZonesToConfig(defaultContext context.Context, config *config.Config) error {
 	computeService, err := compute.NewService(defaultContext, option.WithTokenSource(config.GCPToken))
 	if err != nil {
-		log.Fatalf("AddZonesToConfig.NewService: %s", err)
-	}
-	zones, err := computeService.Zones.List(project).Do()
-	if err != nil {
-		log.Fatalf("AddZonesToConfig.List: %s", err)
+		return fmt.Errorf("AddZonesToConfig.NewService: %s", err)
 	}
+	slog.Debug("Retrieving zones", logProject, config.Project)
 	config.Zones = []string{}
-	for _, zone := range zones.Items {
-		zoneNameSplit := strings.Split(zone.Name, "/")
-		config.Zones = append(config.Zones, zoneNameSplit[len(zoneNameSplit)-1])
+	err = computeService.Zones.List(config.Project).Pages(defaultContext, func(page *compute.ZoneList) error {
+		for _, zone := range page.Items {
+			zoneNameSplit := strings.Split(zone.Name, "/")
+			config.Zones = append(config.Zones, zoneNameSplit[len(zoneNameSplit)-1])
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("AddZonesToConfig.List: %s", err)
 	}
+	return nil
 }
 
-func AddRegionsToConfig(defaultContext context.Context, project string, config config.Config) {
+// AddRegionsToConfig - populates the regions available to the configured project
+func AddRegionsToConfig(defaultContext context.Context, config *config.Config) error {
 	computeService, err := compute.NewService(defaultContext, option.WithTokenSource(config.GCPToken))
 	if err != nil {
-		log.Fatalf("AddRegionsToConfig.NewService: %s", err)
-	}
-	regions, err := computeService.Regions.List(project).Do()
-	if err != nil {
-		log.Fatalf("AddRegionsToConfig.List: %s", err)
+		return fmt.Errorf("AddRegionsToConfig.NewService: %s", err)
 	}
+	slog.Debug("Retrieving regions", logProject, config.Project)
 	config.Regions = []string{}
-	for _, region := range regions.Items {
-		regionNameSplit := strings.Split(region.Name, "/")
-		config.Regions = append(config.Regions, regionNameSplit[len(regionNameSplit)-1])
+	err = computeService.Regions.List(config.Project).Pages(defaultContext, func(page *compute.RegionList) error {
+		for _, region := range page.Items {
+			regionNameSplit := strings.Split(region.Name, "/")
+			config.Regions = append(config.Regions, regionNameSplit[len(regionNameSplit)-1])
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("AddRegionsToConfig.List: %s", err)
 	}
+	return nil
 }
 
 func extractGKESelfLink(input string) string {
@@ -0,0 +1,120 @@
+package gcp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BESTSELLER/gcp-nuke/helpers"
+)
+
+// Item - a single listed resource, the same shape for every resource type
+type Item struct {
+	// Type - name of the resource type the item belongs to
+	Type string `json:"type"`
+	// Name - short name as shown in the console, e.g. an instance name or a dataset id
+	Name string `json:"name"`
+	// FullName - relative resource name and the key of the item within its type, e.g. projects/p/zones/z/instances/i
+	FullName string `json:"full_name"`
+	Project  string `json:"project"`
+	// Location - zone, region or multi-region of the item, empty for global resources
+	Location string            `json:"location,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	// CreatedAt - creation time, zero when the API returns none
+	CreatedAt time.Time `json:"created_at,omitzero"`
+	// Parents - full names of the resources the item belongs to, e.g. the network of a subnetwork
+	Parents []string `json:"parents,omitempty"`
+	// Fingerprint - server assigned id or etag that changes when the resource is recreated, or digest of its configuration when the API returns neither
+	Fingerprint string `json:"fingerprint,omitempty"`
+}
+
+// state - what apply compares against the plan to refuse items that changed since: the fingerprint, labels and creation time
+func (i Item) state() string {
+	state, _ := json.Marshal(struct {
+		Fingerprint string            `json:"fingerprint,omitempty"`
+		Labels      map[string]string `json:"labels,omitempty"`
+		CreatedAt   time.Time         `json:"created_at,omitzero"`
+	}{i.Fingerprint, i.Labels, i.CreatedAt})
+	return string(state)
+}
+
+// digest - a fingerprint for resources without an id or etag, hashed from their configuration as the API returns it
+func digest(resource json.Marshaler) string {
+	data, err := resource.MarshalJSON()
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// reset - forgets every listed item before a fresh list
+func (b *ResourceBase) reset() {
+	b.items.Range(func(key, value interface{}) bool {
+		b.items.Delete(key)
+		return true
+	})
+}
+
+// track - remembers a listed item
+func (b *ResourceBase) track(item Item) {
+	b.items.Store(item.FullName, item)
+}
+
+// forget - drops an item once it is deleted
+func (b *ResourceBase) forget(item Item) {
+	b.items.Delete(item.FullName)
+}
+
+// listed - the listed items that are not deleted yet, sorted by full name
+func (b *ResourceBase) listed() []Item {
+	items := []Item{}
+	b.items.Range(func(key, value interface{}) bool {
+		items = append(items, value.(Item))
+		return true
+	})
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].FullName < items[j].FullName
+	})
+	return items
+}
+
+// fullNames - full names of the listed items, sorted
+func (b *ResourceBase) fullNames() []string {
+	return helpers.SortedSyncMapKeys(&b.items)
+}
+
+// relativeName - turns an API self link into a relative resource name
+func relativeName(selfLink string) string {
+	index := strings.Index(selfLink, "/projects/")
+	if index == -1 {
+		return selfLink
+	}
+	return selfLink[index+1:]
+}
+
+// relativeNames - relative resource names of the non empty self links
+func relativeNames(selfLinks ...string) []string {
+	names := []string{}
+	for _, selfLink := range selfLinks {
+		if selfLink != "" {
+			names = append(names, relativeName(selfLink))
+		}
+	}
+	return names
+}
+
+// computeID - formats a compute resource id as a fingerprint
+func computeID(id uint64) string {
+	return strconv.FormatUint(id, 10)
+}
+
+// aggregatedScope - the location of an AggregatedList scope such as zones/europe-west1-b, and whether it is of the given kind and one of the configured locations
+func aggregatedScope(scope, kind string, locations []string) (string, bool) {
+	location, found := strings.CutPrefix(scope, kind+"/")
+	return location, found && helpers.SliceContains(locations, location)
+}
@@ -0,0 +1,26 @@
+package gcp
+
+// Keys of the fields on log lines, so that lines about the same resource can be found and filtered together
+const (
+	logProject   = "project"
+	logType      = "type"
+	logName      = "name"
+	logLocation  = "location"
+	logOperation = "operation"
+	logElapsed   = "elapsed_seconds"
+	logError     = "error"
+)
+
+// itemLog - the log fields of an item, name being its full name. Global items have no location field
+func itemLog(item Item) []any {
+	fields := []any{logProject, item.Project, logType, item.Type, logName, item.FullName}
+	if item.Location != "" {
+		fields = append(fields, logLocation, item.Location)
+	}
+	return fields
+}
+
+// typeLog - the log fields of a resource type in a project
+func typeLog(resource Resource, project string) []any {
+	return []any{logProject, project, logType, resource.Name()}
+}
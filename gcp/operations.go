@@ -0,0 +1,139 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"strings"
+	"time"
+
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/container/v1"
+)
+
+// maxOperationBackoff - upper bound for the wait between polls of a long running operation
+const maxOperationBackoff = 30 * time.Second
+
+// OperationError - a long running operation that finished with errors
+type OperationError struct {
+	Operation string
+	// Codes - error codes reported by the operation, e.g. RESOURCE_IN_USE_BY_ANOTHER_RESOURCE
+	Codes    []string
+	Messages []string
+}
+
+func (e *OperationError) Error() string {
+	return fmt.Sprintf("operation %v failed: %v", e.Operation, strings.Join(e.Messages, "; "))
+}
+
+// waitFor - calls poll until it reports done or fails, backing off exponentially with jitter between calls.
+// Gives up after the configured timeout or when the context is cancelled, and returns the seconds waited. operation is the id logged while waiting, if any
+func (b *ResourceBase) waitFor(ctx context.Context, item Item, operation string, poll func(ctx context.Context) (bool, error)) (int, error) {
+	start := time.Now()
+	timeout := time.Duration(b.config.Timeout) * time.Second
+	backoff := time.Duration(b.config.PollTime) * time.Second
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+
+	for {
+		seconds := int(time.Since(start).Seconds())
+		if err := b.config.Limiter.Wait(ctx); err != nil {
+			return seconds, err
+		}
+		done, err := poll(ctx)
+		if done {
+			return seconds, err
+		}
+		// A flaky status call is retried, the operation itself is still running
+		if err != nil && !classifyError(err).backedOff() {
+			return seconds, err
+		}
+
+		if time.Since(start) > timeout {
+			return seconds, fmt.Errorf("resource deletion timed out for %v after %v seconds", item.FullName, b.config.Timeout)
+		}
+		slog.Info("Resource currently being deleted", append(itemLog(item), logOperation, operation, logElapsed, seconds)...)
+
+		// Equal jitter, half the backoff plus a random part of the other half, keeps parallel pollers apart without polling too early
+		sleep := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		// A rate limited status call waits at least as long as the API asked for
+		sleep = max(sleep, retryDelay(err))
+		select {
+		case <-ctx.Done():
+			return seconds, ctx.Err()
+		case <-time.After(sleep):
+		}
+		backoff = min(backoff*2, maxOperationBackoff)
+	}
+}
+
+// waitForComputeOperation - waits for a zonal, regional or global compute operation using the server side Wait endpoints
+func (b *ResourceBase) waitForComputeOperation(ctx context.Context, item Item, service *compute.Service, operation *compute.Operation) (int, error) {
+	project := b.config.Project
+
+	return b.waitFor(ctx, item, operation.Name, func(ctx context.Context) (bool, error) {
+		if operation.Status != "DONE" {
+			var err error
+			var current *compute.Operation
+			switch {
+			case operation.Zone != "":
+				current, err = service.ZoneOperations.Wait(project, lastSegment(operation.Zone), operation.Name).Context(ctx).Do()
+			case operation.Region != "":
+				current, err = service.RegionOperations.Wait(project, lastSegment(operation.Region), operation.Name).Context(ctx).Do()
+			default:
+				current, err = service.GlobalOperations.Wait(project, operation.Name).Context(ctx).Do()
+			}
+			if err != nil {
+				return false, err
+			}
+			operation = current
+		}
+		if operation.Status != "DONE" {
+			return false, nil
+		}
+		return true, computeOperationError(operation)
+	})
+}
+
+// waitForContainerOperation - waits for a GKE operation, the container API has no server side Wait endpoint
+func (b *ResourceBase) waitForContainerOperation(ctx context.Context, item Item, service *container.Service, operationName string) (int, error) {
+	name := fmt.Sprintf("projects/%v/locations/%v/operations/%v", b.config.Project, item.Location, operationName)
+
+	return b.waitFor(ctx, item, operationName, func(ctx context.Context) (bool, error) {
+		operation, err := service.Projects.Locations.Operations.Get(name).Context(ctx).Do()
+		if err != nil {
+			return false, err
+		}
+		if operation.Status != "DONE" {
+			return false, nil
+		}
+		if operation.Error != nil && operation.Error.Message != "" {
+			return true, &OperationError{
+				Operation: operationName,
+				Codes:     []string{fmt.Sprint(operation.Error.Code)},
+				Messages:  []string{operation.Error.Message},
+			}
+		}
+		return true, nil
+	})
+}
+
+// computeOperationError - the errors of a finished compute operation, nil when it succeeded
+func computeOperationError(operation *compute.Operation) error {
+	if operation.Error == nil || len(operation.Error.Errors) == 0 {
+		return nil
+	}
+	operationError := &OperationError{Operation: operation.Name}
+	for _, item := range operation.Error.Errors {
+		operationError.Codes = append(operationError.Codes, item.Code)
+		operationError.Messages = append(operationError.Messages, item.Message)
+	}
+	return operationError
+}
+
+// lastSegment - the last part of a resource URL, e.g. the zone name of a zone URL
+func lastSegment(url string) string {
+	return url[strings.LastIndex(url, "/")+1:]
+}
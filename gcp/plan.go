@@ -0,0 +1,166 @@
+package gcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/BESTSELLER/gcp-nuke/config"
+)
+
+// PlanVersion - version of the plan file format
+const PlanVersion = 3
+
+// Plan - the exact set of resources a later apply may delete
+type Plan struct {
+	Version   int           `json:"version"`
+	CreatedAt time.Time     `json:"created_at"`
+	Projects  []ProjectPlan `json:"projects"`
+}
+
+// ProjectPlan - planned resources of one project, in dependency order
+type ProjectPlan struct {
+	Project   string            `json:"project"`
+	Resources []PlannedResource `json:"resources"`
+}
+
+// PlannedResource - planned items of one resource type
+type PlannedResource struct {
+	Type         string   `json:"type"`
+	Dependencies []string `json:"dependencies"`
+	Items        []Item   `json:"items"`
+}
+
+// PlanProjects - lists every project and records what a run would delete
+func PlanProjects(registry *Registry, baseConfig config.Config, projects []string, maxParallel int) (Plan, []ProjectResult) {
+	baseConfig.Planning = true
+	plan := Plan{
+		Version:   PlanVersion,
+		CreatedAt: time.Now().UTC(),
+		Projects:  make([]ProjectPlan, len(projects)),
+	}
+	var mutex sync.Mutex
+	index := map[string]int{}
+	for i, project := range projects {
+		index[project] = i
+	}
+
+	results := forEachProject(baseConfig, projects, maxParallel, func(projectConfig config.Config) error {
+		projectPlan, err := planProject(registry, projectConfig)
+		mutex.Lock()
+		plan.Projects[index[projectConfig.Project]] = projectPlan
+		mutex.Unlock()
+		return err
+	})
+	return plan, results
+}
+
+func planProject(registry *Registry, config config.Config) (ProjectPlan, error) {
+	projectPlan := ProjectPlan{
+		Project:   config.Project,
+		Resources: []PlannedResource{},
+	}
+	resourceMap, err := registry.Resources(config)
+	if err != nil {
+		return projectPlan, err
+	}
+	order, err := dependencyOrder(resourceMap)
+	if err != nil {
+		return projectPlan, err
+	}
+
+	for _, name := range order {
+		if interrupted(config) {
+			return projectPlan, ErrInterrupted
+		}
+		resource := resourceMap[name]
+		if !serviceEnabled(config, resource.Service()) {
+			skipDisabledService(resource, config)
+			continue
+		}
+		slog.Debug("Retrieving list of resources", typeLog(resource, config.Project)...)
+		items, err := listResource(resource, config)
+		if classifyError(err) == ErrorServiceDisabled {
+			skipDisabledService(resource, config)
+			continue
+		}
+		if err != nil {
+			// A plan missing a type would silently keep its resources, so the whole project fails
+			return projectPlan, err
+		}
+		projectPlan.Resources = append(projectPlan.Resources, PlannedResource{
+			Type:         name,
+			Dependencies: resource.Dependencies(),
+			Items:        items,
+		})
+		slog.Info("Plan: resources will be destroyed", append(typeLog(resource, config.Project), "items", resource.ToSlice())...)
+	}
+	return projectPlan, nil
+}
+
+// ApplyPlan - deletes only the planned resources, refusing any that appeared or changed since the plan was made, and verifies that the planned ones are gone.
+// The exclusions of baseConfig still apply, so a resource that is protected since the plan is kept
+func ApplyPlan(registry *Registry, baseConfig config.Config, plan Plan, maxParallel int) []ProjectResult {
+	planned := map[string]map[string]map[string]string{}
+	projects := []string{}
+	for _, projectPlan := range plan.Projects {
+		projects = append(projects, projectPlan.Project)
+		planned[projectPlan.Project] = map[string]map[string]string{}
+		for _, resource := range projectPlan.Resources {
+			items := map[string]string{}
+			for _, item := range resource.Items {
+				items[item.FullName] = item.state()
+			}
+			planned[projectPlan.Project][resource.Type] = items
+		}
+	}
+
+	baseConfig.Planning = true
+	return removeVerified(registry, baseConfig, projects, maxParallel, func(projectConfig *config.Config) {
+		projectConfig.Planned = planned[projectConfig.Project]
+	})
+}
+
+// Types - every resource type planned in any project, sorted by name
+func (p Plan) Types() []string {
+	types := []string{}
+	for _, projectPlan := range p.Projects {
+		for _, resource := range projectPlan.Resources {
+			if !slices.Contains(types, resource.Type) {
+				types = append(types, resource.Type)
+			}
+		}
+	}
+	sort.Strings(types)
+	return types
+}
+
+// WritePlan - saves a plan as indented json
+func WritePlan(path string, plan Plan) error {
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// ReadPlan - loads a plan written by WritePlan
+func ReadPlan(path string) (Plan, error) {
+	plan := Plan{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return plan, err
+	}
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return plan, fmt.Errorf("plan file could not be parsed: %v", err)
+	}
+	if plan.Version != PlanVersion {
+		return plan, fmt.Errorf("unsupported plan version %v, expected %v", plan.Version, PlanVersion)
+	}
+	return plan, nil
+}
@@ -0,0 +1,57 @@
+package gcp
+
+import (
+	"maps"
+	"testing"
+	"time"
+
+	"github.com/BESTSELLER/gcp-nuke/config"
+)
+
+func TestPlanFiltered(t *testing.T) {
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	planned := Item{
+		Type:        "ComputeDisks",
+		Name:        "disk",
+		FullName:    "projects/p/zones/z/disks/disk",
+		Labels:      map[string]string{"env": "test", "team": "data"},
+		CreatedAt:   created,
+		Fingerprint: "123",
+	}
+	changed := func(change func(*Item)) Item {
+		item := planned
+		item.Labels = maps.Clone(planned.Labels)
+		change(&item)
+		return item
+	}
+
+	tests := []struct {
+		name string
+		item Item
+		want bool
+	}{
+		{"unchanged", planned, false},
+		{"labels listed in another order", changed(func(i *Item) { i.Labels = map[string]string{"team": "data", "env": "test"} }), false},
+		{"appeared since the plan", changed(func(i *Item) { i.FullName = "projects/p/zones/z/disks/other" }), true},
+		{"recreated", changed(func(i *Item) { i.Fingerprint = "456" }), true},
+		{"relabeled", changed(func(i *Item) { i.Labels["keep"] = "true" }), true},
+		{"created again", changed(func(i *Item) { i.CreatedAt = created.Add(time.Hour) }), true},
+		{"type not in the plan", changed(func(i *Item) { i.Type = "ComputeFirewalls" }), true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := &ResourceBase{config: config.Config{Planned: map[string]map[string]string{
+				planned.Type: {planned.FullName: planned.state()},
+			}}}
+			if got := b.planFiltered(test.item); got != test.want {
+				t.Errorf("planFiltered = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestItemStateIgnoresEmptyLabels(t *testing.T) {
+	if (Item{Labels: map[string]string{}}).state() != (Item{}).state() {
+		t.Errorf("empty labels change the state, they are dropped when a plan is written")
+	}
+}
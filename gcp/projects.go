@@ -0,0 +1,168 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BESTSELLER/gcp-nuke/config"
+	"golang.org/x/oauth2"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/api/cloudresourcemanager/v3"
+	"google.golang.org/api/option"
+)
+
+// ProjectResult - outcome of nuking a single project
+type ProjectResult struct {
+	Project  string
+	Duration time.Duration
+	Err      error
+	// Verdict - what was left after deleting, nil when the project was not verified
+	Verdict *Verdict
+}
+
+// DiscoverProjects - returns the ids of all active projects below a folder or organization, including nested folders
+func DiscoverProjects(defaultContext context.Context, token oauth2.TokenSource, parent string) ([]string, error) {
+	resourceManager, err := cloudresourcemanager.NewService(defaultContext, option.WithTokenSource(token))
+	if err != nil {
+		return nil, fmt.Errorf("DiscoverProjects.NewService: %s", err)
+	}
+
+	projects := []string{}
+	parents := []string{parent}
+	for len(parents) > 0 {
+		current := parents[0]
+		parents = parents[1:]
+
+		err := resourceManager.Projects.List().Parent(current).Pages(defaultContext, func(page *cloudresourcemanager.ListProjectsResponse) error {
+			for _, project := range page.Projects {
+				if project.State == "ACTIVE" {
+					projects = append(projects, project.ProjectId)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("DiscoverProjects.Projects.List(%v): %s", current, err)
+		}
+
+		err = resourceManager.Folders.List().Parent(current).Pages(defaultContext, func(page *cloudresourcemanager.ListFoldersResponse) error {
+			for _, folder := range page.Folders {
+				if folder.State == "ACTIVE" {
+					parents = append(parents, folder.Name)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("DiscoverProjects.Folders.List(%v): %s", current, err)
+		}
+	}
+
+	sort.Strings(projects)
+	return projects, nil
+}
+
+// FolderParent - normalises a folder id into a resource manager parent
+func FolderParent(folder string) string {
+	return "folders/" + strings.TrimPrefix(folder, "folders/")
+}
+
+// OrganizationParent - normalises an organization id into a resource manager parent
+func OrganizationParent(organization string) string {
+	return "organizations/" + strings.TrimPrefix(organization, "organizations/")
+}
+
+// RemoveProjects - nukes and verifies each project with its own registry instances, running at most maxParallel projects at once
+func RemoveProjects(registry *Registry, baseConfig config.Config, projects []string, maxParallel int) []ProjectResult {
+	return removeVerified(registry, baseConfig, projects, maxParallel, func(*config.Config) {})
+}
+
+// forEachProject - runs action for each project with zones and regions populated, running at most maxParallel projects at once
+func forEachProject(baseConfig config.Config, projects []string, maxParallel int, action func(config.Config) error) []ProjectResult {
+	if maxParallel < 1 {
+		maxParallel = 1
+	}
+
+	if baseConfig.Drain == nil {
+		baseConfig.Drain = baseConfig.Context
+	}
+
+	results := make([]ProjectResult, len(projects))
+	var mutex sync.Mutex
+
+	errs, _ := errgroup.WithContext(baseConfig.Context)
+	errs.SetLimit(maxParallel)
+
+	for i, project := range projects {
+		i, project := i, project
+		errs.Go(func() error {
+			start := time.Now()
+			projectConfig := baseConfig
+			projectConfig.Project = project
+
+			var err error
+			if interrupted(projectConfig) {
+				slog.Warn("Interrupted, project was not started", logProject, project)
+				err = ErrInterrupted
+			}
+			if err == nil {
+				// Without Service Usage every resource type is tried, and a disabled API shows up when listing
+				if servicesErr := AddServicesToConfig(projectConfig.Context, &projectConfig); servicesErr != nil {
+					slog.Warn("Enabled services unknown", logProject, project, logError, servicesErr)
+				}
+			}
+			// Zones and regions come from the compute API, which may be disabled
+			if err == nil && serviceEnabled(projectConfig, computeAPI) {
+				err = AddZonesToConfig(projectConfig.Context, &projectConfig)
+			}
+			if err == nil && serviceEnabled(projectConfig, computeAPI) {
+				err = AddRegionsToConfig(projectConfig.Context, &projectConfig)
+			}
+			if err == nil {
+				err = action(projectConfig)
+			}
+			if err != nil {
+				slog.Error("Project failed", logProject, project, logError, err)
+			}
+
+			mutex.Lock()
+			results[i] = ProjectResult{
+				Project:  project,
+				Duration: time.Since(start),
+				Err:      err,
+			}
+			mutex.Unlock()
+			// Failures are reported in the summary, they never stop other projects
+			return nil
+		})
+	}
+	errs.Wait()
+
+	return results
+}
+
+// LogProjectSummary - prints one line per project and returns the number of failed projects
+func LogProjectSummary(results []ProjectResult, dryRun bool) int {
+	failed := 0
+	slog.Info("-- Summary --", "projects", len(results), "dry_run", dryRun)
+	for _, result := range results {
+		if errors.Is(result.Err, ErrInterrupted) {
+			failed++
+			slog.Warn("Project interrupted", logProject, result.Project, logElapsed, int(result.Duration.Seconds()), logError, result.Err)
+			continue
+		}
+		if result.Err != nil {
+			failed++
+			slog.Error("Project failed", logProject, result.Project, logElapsed, int(result.Duration.Seconds()), logError, result.Err)
+			continue
+		}
+		slog.Info("Project ok", logProject, result.Project, logElapsed, int(result.Duration.Seconds()))
+	}
+	return failed
+}
@@ -1,64 +1,28 @@
 package gcp
 
 import (
-	"fmt"
-	"log"
-	"sync"
+	"context"
 
-	"github.com/BESTSELLER/gcp-nuke/config"
-	"github.com/BESTSELLER/gcp-nuke/helpers"
-	"golang.org/x/sync/errgroup"
-	"golang.org/x/sync/syncmap"
 	"google.golang.org/api/option"
 	"google.golang.org/api/pubsub/v1"
 )
 
-type PubSubTopic struct {
-	serviceClient *pubsub.Service
-	base          ResourceBase
-	resourceMap   syncmap.Map
-	TopicIDs      []string
-}
+type PubSubTopic struct{}
 
 func (c *PubSubTopic) Name() string {
 	return "PubSubTopic"
 }
 
-func (c *PubSubTopic) ToSlice() (slice []string) {
-	return helpers.SortedSyncMapKeys(&c.resourceMap)
+func (c *PubSubTopic) Service() string {
+	return "pubsub.googleapis.com"
 }
 
-func (c *PubSubTopic) Setup(config config.Config) {
-	c.base.config = config
-
-	pubsubService, err := pubsub.NewService(Ctx, option.WithTokenSource(config.GCPToken))
-	if err != nil {
-		log.Fatalf("PubSubTopic.Setup.NewService: %s", err)
-	}
-
-	pubsubResource := PubSubTopic{
-		serviceClient: pubsubService,
-	}
-	register(&pubsubResource)
+func (c *PubSubTopic) ConfigKey() string {
+	return "pubsub_topic"
 }
 
-func (c *PubSubTopic) List(refreshCache bool) []string {
-	if !refreshCache {
-		return c.ToSlice()
-	}
-	// Refresh resource map
-	c.resourceMap = sync.Map{}
-
-	topicList, err := c.serviceClient.Projects.Topics.List("projects/" + c.base.config.Project).Context(Ctx).Do()
-	if err != nil {
-		log.Fatalf("PubSubTopic.List: %s", err)
-	}
-
-	for _, topic := range topicList.Topics {
-		c.resourceMap.Store(topic.Name, topic.Name)
-	}
-
-	return c.ToSlice()
+func (c *PubSubTopic) Filters() FilterSupport {
+	return FilterSupport{Labels: true, Age: false}
 }
 
 // Dependencies - Returns a List of resource names to check for
@@ -66,29 +30,33 @@ func (c *PubSubTopic) Dependencies() []string {
 	return []string{}
 }
 
-func (c *PubSubTopic) Remove() error {
-	// Removal logic
-	errs, _ := errgroup.WithContext(c.base.config.Context)
+func (c *PubSubTopic) newClient(ctx context.Context, b *ResourceBase) (*pubsub.Service, error) {
+	return pubsub.NewService(ctx, option.WithTokenSource(b.config.GCPToken))
+}
 
-	c.resourceMap.Range(func(key, value interface{}) bool {
-		topicID := value.(string)
-		fmt.Println(topicID)
-		// location := strings.Split(datasetID, "/")[3]
-		// Parallel instance deletion
-		errs.Go(func() error {
-			_, err := c.serviceClient.Projects.Topics.Delete(topicID).Context(Ctx).Do()
-			if err != nil {
-				return err
-			}
+func (c *PubSubTopic) listPage(ctx context.Context, b *ResourceBase, client *pubsub.Service, pageToken string) ([]Item, string, error) {
+	page, err := client.Projects.Topics.List("projects/" + b.config.Project).PageToken(pageToken).Context(ctx).Do()
+	if err != nil {
+		return nil, "", err
+	}
+	items := []Item{}
+	for _, topic := range page.Topics {
+		items = append(items, Item{
+			Name:     lastSegment(topic.Name),
+			FullName: topic.Name,
+			Labels:   topic.Labels,
+			// Topics have neither an id nor an etag
+			Fingerprint: digest(topic),
+		})
+	}
+	return items, page.NextPageToken, nil
+}
 
-			seconds := 0
+func (c *PubSubTopic) deleteOne(ctx context.Context, b *ResourceBase, client *pubsub.Service, item Item) (*pubsub.Empty, error) {
+	return client.Projects.Topics.Delete(item.FullName).Context(ctx).Do()
+}
 
-			log.Printf("[Info] Resource deleted %v [type: %v project: %v] (%v seconds)", topicID, c.Name(), c.base.config.Project, seconds)
-			return nil
-		})
-		return true
-	})
-	// Wait for all deletions to complete, and return the first non nil error
-	err := errs.Wait()
-	return err
+// waitForOp - topics are deleted immediately
+func (c *PubSubTopic) waitForOp(ctx context.Context, b *ResourceBase, client *pubsub.Service, item Item, _ *pubsub.Empty) (int, error) {
+	return 0, nil
 }
@@ -0,0 +1,223 @@
+package gcp
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+
+	"github.com/BESTSELLER/gcp-nuke/config"
+)
+
+// ResourceFactory - returns a new, unconfigured instance of a resource type
+type ResourceFactory func() Resource
+
+// Registry - the set of resource types used by a single run
+type Registry struct {
+	factories map[string]ResourceFactory
+	// unselected - registered types left out by Select, dependencies on them are dropped
+	unselected map[string]bool
+}
+
+// NewRegistry - creates a registry holding the given resource factories
+func NewRegistry(factories ...ResourceFactory) (*Registry, error) {
+	registry := &Registry{
+		factories:  make(map[string]ResourceFactory),
+		unselected: make(map[string]bool),
+	}
+	for _, factory := range factories {
+		if err := registry.Register(factory); err != nil {
+			return nil, err
+		}
+	}
+	if err := registry.Validate(); err != nil {
+		return nil, err
+	}
+	return registry, nil
+}
+
+// NewDefaultRegistry - creates a registry holding every resource type shipped with gcp-nuke
+func NewDefaultRegistry() (*Registry, error) {
+	return NewRegistry(DefaultFactories()...)
+}
+
+// DefaultFactories - factories for every resource type shipped with gcp-nuke
+func DefaultFactories() []ResourceFactory {
+	return []ResourceFactory{
+		func() Resource { return newResource(&BigQueryDataset{}) },
+		func() Resource { return newResource(&ComputeDisks{}) },
+		func() Resource { return newResource(&ComputeFirewalls{}) },
+		func() Resource { return newResource(&ComputeInstanceGroupsRegion{}) },
+		func() Resource { return newResource(&ComputeInstanceGroupsZone{}) },
+		func() Resource { return newResource(&ComputeInstanceTemplates{}) },
+		func() Resource { return newResource(&ComputeInstances{}) },
+		func() Resource { return newResource(&ComputeNetworkPeerings{}) },
+		func() Resource { return newResource(&ComputeNetworks{}) },
+		func() Resource { return newResource(&ComputeRegionAutoScalers{}) },
+		func() Resource { return newResource(&ComputeRouters{}) },
+		func() Resource { return newResource(&ComputeSubnetworks{}) },
+		func() Resource { return newResource(&ComputeVPNGateways{}) },
+		func() Resource { return newResource(&ComputeVPNTunnels{}) },
+		func() Resource { return newResource(&ComputeZoneAutoScalers{}) },
+		func() Resource { return newResource(&ContainerGKEClusters{}) },
+		func() Resource { return newResource(&IAMServiceAccount{}) },
+		func() Resource { return newResource(&PubSubTopic{}) },
+	}
+}
+
+// Register - adds a resource factory, rejecting duplicate resource names
+func (r *Registry) Register(factory ResourceFactory) error {
+	name := factory().Name()
+	if _, exists := r.factories[name]; exists {
+		return fmt.Errorf("a resource with the name %s already exists", name)
+	}
+	r.factories[name] = factory
+	return nil
+}
+
+// Validate - checks that the declared dependencies form a graph without cycles or unknown resource names,
+// and that every config key exists in the config file
+func (r *Registry) Validate() error {
+	resources := make(map[string]Resource, len(r.factories))
+	for name, factory := range r.factories {
+		resource := r.selected(factory())
+		if !slices.Contains(config.ExclusionKeys(), resource.ConfigKey()) {
+			return fmt.Errorf("resource %v uses unknown config key %v", name, resource.ConfigKey())
+		}
+		resources[name] = resource
+	}
+	_, err := dependencyWaves(resources)
+	return err
+}
+
+// Names - sorted names of all registered resource types
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// TypeInfo - a registered resource type as shown by the resource-types command
+type TypeInfo struct {
+	Name         string        `json:"name"`
+	ConfigKey    string        `json:"config_key"`
+	Service      string        `json:"service"`
+	Dependencies []string      `json:"dependencies"`
+	Filters      FilterSupport `json:"filters"`
+}
+
+// Types - every registered resource type, sorted by name
+func (r *Registry) Types() []TypeInfo {
+	types := []TypeInfo{}
+	for _, name := range r.Names() {
+		resource := r.selected(r.factories[name]())
+		types = append(types, TypeInfo{
+			Name:         name,
+			ConfigKey:    resource.ConfigKey(),
+			Service:      resource.Service(),
+			Dependencies: resource.Dependencies(),
+			Filters:      resource.Filters(),
+		})
+	}
+	return types
+}
+
+// Resources - returns fresh resource instances configured for the given config
+func (r *Registry) Resources(config config.Config) (map[string]Resource, error) {
+	resources := make(map[string]Resource, len(r.factories))
+	for name, factory := range r.factories {
+		resource := factory()
+		if err := resource.Setup(config); err != nil {
+			return nil, err
+		}
+		resources[name] = r.selected(resource)
+	}
+	return resources, nil
+}
+
+// Select - returns a registry with only the included types, or every type when include is empty, minus the excluded ones.
+// Dependencies of included types are pulled in unless they are excluded, in which case a warning is logged and the dependency is not waited for
+func (r *Registry) Select(include, exclude []string) (*Registry, error) {
+	for _, name := range append(append([]string{}, include...), exclude...) {
+		if _, exists := r.factories[name]; !exists {
+			return nil, fmt.Errorf("unknown resource type %v, expected one of %v", name, r.Names())
+		}
+	}
+	excluded := map[string]bool{}
+	for _, name := range exclude {
+		excluded[name] = true
+	}
+
+	chosen := map[string]bool{}
+	// neededBy - the first type that pulled in a dependency
+	neededBy := map[string]string{}
+	pending := include
+	if len(pending) == 0 {
+		pending = r.Names()
+	}
+	for len(pending) > 0 {
+		name := pending[0]
+		pending = pending[1:]
+		if chosen[name] || excluded[name] {
+			continue
+		}
+		chosen[name] = true
+		if len(include) > 0 && !slices.Contains(include, name) {
+			slog.Info("Including resource type, a selected type depends on it", logType, name, "needed_by", neededBy[name])
+		}
+		for _, dependency := range r.factories[name]().Dependencies() {
+			if excluded[dependency] {
+				slog.Warn("Resource type depends on an excluded type, items of that type left behind may block its deletion", logType, name, "dependency", dependency)
+				continue
+			}
+			if _, seen := neededBy[dependency]; !seen {
+				neededBy[dependency] = name
+			}
+			pending = append(pending, dependency)
+		}
+	}
+
+	selection := &Registry{
+		factories:  make(map[string]ResourceFactory),
+		unselected: make(map[string]bool),
+	}
+	for name, factory := range r.factories {
+		if chosen[name] {
+			selection.factories[name] = factory
+		} else {
+			selection.unselected[name] = true
+		}
+	}
+	if err := selection.Validate(); err != nil {
+		return nil, err
+	}
+	return selection, nil
+}
+
+// selected - hides dependencies on unselected types, so nothing waits for a type that never runs
+func (r *Registry) selected(resource Resource) Resource {
+	dependencies := []string{}
+	for _, dependency := range resource.Dependencies() {
+		if !r.unselected[dependency] {
+			dependencies = append(dependencies, dependency)
+		}
+	}
+	if len(dependencies) == len(resource.Dependencies()) {
+		return resource
+	}
+	return &selectedResource{Resource: resource, dependencies: dependencies}
+}
+
+// selectedResource - a resource type whose dependencies on unselected types are dropped
+type selectedResource struct {
+	Resource
+	dependencies []string
+}
+
+// Dependencies - the dependencies that are part of the selection
+func (s *selectedResource) Dependencies() []string {
+	return s.dependencies
+}
@@ -0,0 +1,43 @@
+package gcp
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/BESTSELLER/gcp-nuke/report"
+)
+
+// record - adds an untimed entry for an item to the run report
+func (b *ResourceBase) record(item Item, action, reason string) {
+	b.config.Report.Add(report.Entry{
+		Type:     item.Type,
+		Name:     item.Name,
+		Project:  item.Project,
+		Location: item.Location,
+		Action:   action,
+		Reason:   reason,
+	})
+}
+
+// reported - wraps the deletion of a single item so its outcome and duration end up in the run report.
+// An item that is already gone counts as deleted, and is no longer listed
+func (b *ResourceBase) reported(item Item, deletion func() error) func() error {
+	return func() error {
+		start := time.Now()
+		err := deletion()
+		if classifyError(err) == ErrorNotFound {
+			slog.Info("Resource already deleted", itemLog(item)...)
+			b.forget(item)
+			err = nil
+		}
+		b.config.Progress.Finished(item.Project, item.Type, err)
+		b.config.Report.Record(report.Entry{
+			Type:     item.Type,
+			Name:     item.Name,
+			Project:  item.Project,
+			Location: item.Location,
+			Action:   report.Deleted,
+		}, start, err)
+		return err
+	}
+}
@@ -0,0 +1,54 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/BESTSELLER/gcp-nuke/config"
+	"github.com/BESTSELLER/gcp-nuke/report"
+	"google.golang.org/api/option"
+	"google.golang.org/api/serviceusage/v1"
+)
+
+// computeAPI - the service zones and regions are looked up with
+const computeAPI = "compute.googleapis.com"
+
+// AddServicesToConfig - populates the APIs enabled in the configured project using Service Usage
+func AddServicesToConfig(defaultContext context.Context, config *config.Config) error {
+	serviceUsage, err := serviceusage.NewService(defaultContext, option.WithTokenSource(config.GCPToken))
+	if err != nil {
+		return fmt.Errorf("AddServicesToConfig.NewService: %s", err)
+	}
+	slog.Debug("Retrieving enabled services", logProject, config.Project)
+	services := []string{}
+	err = serviceUsage.Services.List("projects/"+config.Project).Filter("state:ENABLED").Pages(defaultContext, func(page *serviceusage.ListServicesResponse) error {
+		for _, service := range page.Services {
+			// Config is optional, the name ends in the service name, e.g. projects/123/services/compute.googleapis.com
+			services = append(services, lastSegment(service.Name))
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("AddServicesToConfig.List: %s", err)
+	}
+	config.Services = services
+	return nil
+}
+
+// serviceEnabled - reports whether an API is enabled in the project, while the enabled services are unknown every API counts as enabled
+func serviceEnabled(config config.Config, service string) bool {
+	return config.Services == nil || slices.Contains(config.Services, service)
+}
+
+// skipDisabledService - logs and reports a resource type that is skipped because its API is disabled in the project
+func skipDisabledService(resource Resource, config config.Config) {
+	slog.Info("Skipped resource type, API disabled", append(typeLog(resource, config.Project), "service", resource.Service())...)
+	config.Report.Add(report.Entry{
+		Type:    resource.Name(),
+		Project: config.Project,
+		Action:  report.Skipped,
+		Reason:  "api disabled",
+	})
+}
@@ -0,0 +1,178 @@
+package gcp
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+
+	"github.com/BESTSELLER/gcp-nuke/config"
+	"github.com/BESTSELLER/gcp-nuke/report"
+)
+
+// Verdict - outcome of listing a project again after its resources were deleted
+type Verdict struct {
+	Project string
+	// Survivors - items that still exist although no exclusion keeps them, in dependency order
+	Survivors []Item
+	// Unverified - resource types that could not be listed, with the error
+	Unverified map[string]error
+}
+
+// Passed - reports whether nothing survived and every resource type could be listed
+func (v *Verdict) Passed() bool {
+	return len(v.Survivors) == 0 && len(v.Unverified) == 0
+}
+
+// VerificationError - a verification that did not pass
+type VerificationError struct {
+	Verdict *Verdict
+}
+
+func (e *VerificationError) Error() string {
+	unverified := []string{}
+	for name := range e.Verdict.Unverified {
+		unverified = append(unverified, name)
+	}
+	sort.Strings(unverified)
+	return fmt.Sprintf("verification failed: %v resource(s) survived, resource types not verified: %v", len(e.Verdict.Survivors), unverified)
+}
+
+// removeAndVerify - removes the resources of a project and, unless this is a dryrun or it was interrupted, proves that they are gone.
+// The verdict is nil when the project was not verified. A failed verification is returned as VerificationError, joined with the error of the removal if there was one
+func removeAndVerify(registry *Registry, config config.Config) (*Verdict, error) {
+	err := RemoveProject(registry, config)
+	if !config.Verify || config.DryRun || errors.Is(err, ErrInterrupted) {
+		return nil, err
+	}
+	verdict, verifyErr := VerifyProject(registry, config)
+	if verifyErr != nil {
+		return nil, errors.Join(err, fmt.Errorf("VerifyProject: %w", verifyErr))
+	}
+	if verdict.Passed() {
+		return verdict, err
+	}
+	if err == nil {
+		return verdict, &VerificationError{Verdict: verdict}
+	}
+	return verdict, errors.Join(err, &VerificationError{Verdict: verdict})
+}
+
+// removeVerified - removes and verifies each project, running at most maxParallel projects at once. prepare adjusts the config of a project first
+func removeVerified(registry *Registry, baseConfig config.Config, projects []string, maxParallel int, prepare func(*config.Config)) []ProjectResult {
+	var mutex sync.Mutex
+	verdicts := map[string]*Verdict{}
+	results := forEachProject(baseConfig, projects, maxParallel, func(projectConfig config.Config) error {
+		prepare(&projectConfig)
+		verdict, err := removeAndVerify(registry, projectConfig)
+		mutex.Lock()
+		verdicts[projectConfig.Project] = verdict
+		mutex.Unlock()
+		return err
+	})
+	for i := range results {
+		results[i].Verdict = verdicts[results[i].Project]
+	}
+	return results
+}
+
+// VerifyProject - lists every resource type again with a fresh cache and collects what the exclusions do not account for.
+// Survivors are reported as failed with reason "survived verification", keeping the error of a failed deletion
+func VerifyProject(registry *Registry, config config.Config) (*Verdict, error) {
+	verdict := &Verdict{
+		Project:    config.Project,
+		Unverified: map[string]error{},
+	}
+	// Listing would reset the counts of the last deletion in the progress, survivors are counted as pending instead
+	tracker := config.Progress
+	config.Progress = nil
+	resourceMap, err := registry.Resources(config)
+	if err != nil {
+		return nil, err
+	}
+	order, err := dependencyOrder(resourceMap)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("Verifying project", logProject, config.Project)
+	for _, name := range order {
+		resource := resourceMap[name]
+		if !serviceEnabled(config, resource.Service()) {
+			continue
+		}
+		items, err := listResource(resource, config)
+		if classifyError(err) == ErrorServiceDisabled {
+			continue
+		}
+		if err != nil {
+			slog.Error("Resource type not verified, it could not be listed", append(typeLog(resource, config.Project), logError, err)...)
+			verdict.Unverified[name] = err
+			continue
+		}
+		for _, item := range items {
+			slog.Error("Resource survived", itemLog(item)...)
+			// Keeps the error and duration of a deletion that failed before
+			config.Report.Fail(report.Entry{
+				Type:     item.Type,
+				Name:     item.Name,
+				Project:  item.Project,
+				Location: item.Location,
+				Reason:   "survived verification",
+			})
+		}
+		tracker.Verified(config.Project, name, len(items))
+		verdict.Survivors = append(verdict.Survivors, items...)
+	}
+
+	if verdict.Passed() {
+		slog.Info("Verification passed, nothing left that is not excluded", logProject, config.Project)
+	} else {
+		slog.Error("Verification failed", logProject, config.Project, "survivors", len(verdict.Survivors), "unverified_types", len(verdict.Unverified))
+	}
+	return verdict, nil
+}
+
+// LogVerdict - prints the verdict over every project of a run, with its survivors, and reports whether it passed.
+// Projects that were not verified, e.g. after an interruption, fail the verdict
+func LogVerdict(results []ProjectResult) bool {
+	passed := true
+	slog.Info("-- Verification --")
+	for _, result := range results {
+		switch {
+		case result.Verdict == nil:
+			passed = false
+			slog.Error("Project not verified", logProject, result.Project)
+		case result.Verdict.Passed():
+			slog.Info("Project verified empty", logProject, result.Project)
+		default:
+			passed = false
+			slog.Error("Project verification failed", logProject, result.Project, "survivors", len(result.Verdict.Survivors), "unverified_types", len(result.Verdict.Unverified))
+			for _, item := range result.Verdict.Survivors {
+				slog.Error("Resource survived", itemLog(item)...)
+			}
+		}
+	}
+	if passed {
+		slog.Info("-- Verdict: PASS --", "projects", len(results))
+	} else {
+		slog.Error("-- Verdict: FAIL --", "projects", len(results))
+	}
+	return passed
+}
+
+// OnlyVerificationFailed - reports whether every failed project was deleted without errors, but did not pass verification
+func OnlyVerificationFailed(results []ProjectResult) bool {
+	found := false
+	for _, result := range results {
+		if result.Err == nil {
+			continue
+		}
+		if _, ok := result.Err.(*VerificationError); !ok {
+			return false
+		}
+		found = true
+	}
+	return found
+}
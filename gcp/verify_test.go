@@ -0,0 +1,70 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/BESTSELLER/gcp-nuke/config"
+	"github.com/BESTSELLER/gcp-nuke/report"
+)
+
+// stuckDisks - a resource type whose single item can be listed but never deleted
+type stuckDisks struct{}
+
+func (d *stuckDisks) Name() string           { return "StuckDisk" }
+func (d *stuckDisks) Service() string        { return "compute.googleapis.com" }
+func (d *stuckDisks) ConfigKey() string      { return "compute_disk" }
+func (d *stuckDisks) Filters() FilterSupport { return FilterSupport{} }
+func (d *stuckDisks) Dependencies() []string { return nil }
+
+func (d *stuckDisks) newClient(ctx context.Context, b *ResourceBase) (struct{}, error) {
+	return struct{}{}, nil
+}
+
+func (d *stuckDisks) listPage(ctx context.Context, b *ResourceBase, client struct{}, pageToken string) ([]Item, string, error) {
+	return []Item{{Name: "disk", FullName: "projects/p/zones/z/disks/disk", Location: "z"}}, "", nil
+}
+
+func (d *stuckDisks) deleteOne(ctx context.Context, b *ResourceBase, client struct{}, item Item) (struct{}, error) {
+	return struct{}{}, apiError(http.StatusBadRequest)
+}
+
+func (d *stuckDisks) waitForOp(ctx context.Context, b *ResourceBase, client struct{}, item Item, operation struct{}) (int, error) {
+	return 0, nil
+}
+
+func TestRemoveAndVerifyKeepsDeletionError(t *testing.T) {
+	registry, err := NewRegistry(func() Resource { return newResource(&stuckDisks{}) })
+	if err != nil {
+		t.Fatalf("NewRegistry: %v", err)
+	}
+	runReport := report.New()
+	verdict, err := removeAndVerify(registry, config.Config{
+		Project:   "p",
+		Context:   context.Background(),
+		MaxPasses: 1,
+		Verify:    true,
+		Report:    runReport,
+	})
+	var verificationErr *VerificationError
+	if !errors.As(err, &verificationErr) {
+		t.Fatalf("removeAndVerify error = %v, want a VerificationError", err)
+	}
+	if verdict == nil || len(verdict.Survivors) != 1 {
+		t.Fatalf("verdict = %+v, want one survivor", verdict)
+	}
+
+	entries := runReport.Entries()
+	if len(entries) != 1 {
+		t.Fatalf("report entries = %+v, want one", entries)
+	}
+	entry := entries[0]
+	if entry.Action != report.Failed || entry.Reason != "survived verification" {
+		t.Errorf("entry action %q reason %q, want %q %q", entry.Action, entry.Reason, report.Failed, "survived verification")
+	}
+	if entry.Error == "" {
+		t.Errorf("entry lost the error of the failed deletion")
+	}
+}
@@ -1,7 +1,9 @@
 package helpers
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 	"os"
 	"os/signal"
 	"sort"
@@ -42,13 +44,20 @@ func SortedSyncMapKeys(parMap *syncmap.Map) (output []string) {
 	return output
 }
 
-// SetupCloseHandler - allows manual termination
-func SetupCloseHandler() {
+// SetupCloseHandler - allows manual termination. The first Ctrl+C cancels the drain context so no new work starts,
+// the second cancels the abort context so running work is abandoned. Abort implies drain
+func SetupCloseHandler(parent context.Context) (drain context.Context, abort context.Context) {
+	abort, cancelAbort := context.WithCancel(parent)
+	drain, cancelDrain := context.WithCancel(abort)
 	c := make(chan os.Signal, 2)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	go func() {
 		<-c
-		fmt.Println("\r- Ctrl+C pressed in Terminal - premature termination")
-		os.Exit(1)
+		slog.Warn("Ctrl+C pressed, waiting for running deletions, press again to abort them")
+		cancelDrain()
+		<-c
+		slog.Warn("Ctrl+C pressed again, aborting running deletions")
+		cancelAbort()
 	}()
+	return drain, abort
 }
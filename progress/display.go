@@ -0,0 +1,86 @@
+package progress
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Display - redraws a tracker in place on a terminal. Whatever is written to it, e.g. log lines, is printed above the status lines
+type Display struct {
+	mutex   sync.Mutex
+	out     *os.File
+	tracker *Tracker
+	// lines - number of status lines currently drawn
+	lines int
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// IsTerminal - reports whether file is a terminal rather than a pipe or a regular file
+func IsTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// Start - draws the tracker to out every interval until Stop is called
+func Start(out *os.File, tracker *Tracker, interval time.Duration) *Display {
+	display := &Display{
+		out:     out,
+		tracker: tracker,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go func() {
+		defer close(display.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-display.stop:
+				return
+			case <-ticker.C:
+				display.mutex.Lock()
+				display.redraw(nil)
+				display.mutex.Unlock()
+			}
+		}
+	}()
+	return display
+}
+
+// Write - prints p above the status lines
+func (d *Display) Write(p []byte) (int, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.redraw(p)
+	return len(p), nil
+}
+
+// Stop - draws the final state and leaves it on the terminal
+func (d *Display) Stop() {
+	close(d.stop)
+	<-d.done
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.redraw(nil)
+	d.lines = 0
+}
+
+// redraw - clears the status lines, prints above before redrawing them. Needs the mutex.
+// Built in one buffer so the terminal never shows a half drawn state
+func (d *Display) redraw(above []byte) {
+	var buffer bytes.Buffer
+	if d.lines > 0 {
+		// Move to the first status line and clear everything below it
+		fmt.Fprintf(&buffer, "\x1b[%dA\r\x1b[J", d.lines)
+	}
+	buffer.Write(above)
+	d.lines = d.tracker.Render(&buffer)
+	d.out.Write(buffer.Bytes())
+}
@@ -0,0 +1,154 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// counts - the items of one resource type in one project
+type counts struct {
+	project      string
+	resourceType string
+	pending      int
+	deleting     int
+	deleted      int
+	excluded     int
+	failed       int
+	started      time.Time
+	// finished - zero while the type is still running
+	finished time.Time
+}
+
+// Tracker - counts the items of every resource type of a run by state, safe for concurrent use. A nil Tracker ignores every call
+type Tracker struct {
+	mutex sync.Mutex
+	start time.Time
+	types map[string]*counts
+}
+
+// New - creates a tracker, its elapsed time starts now
+func New() *Tracker {
+	return &Tracker{
+		start: time.Now(),
+		types: make(map[string]*counts),
+	}
+}
+
+// get - the counts of a type, created on first use. Needs the mutex
+func (t *Tracker) get(project, resourceType string) *counts {
+	key := project + "/" + resourceType
+	entry, found := t.types[key]
+	if !found {
+		entry = &counts{project: project, resourceType: resourceType, started: time.Now()}
+		t.types[key] = entry
+	}
+	return entry
+}
+
+// Listed - a type was listed again. What is left is pending, and earlier failures are retried. Deleted items are kept
+func (t *Tracker) Listed(project, resourceType string, pending, excluded int) {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	entry := t.get(project, resourceType)
+	entry.pending = pending
+	entry.excluded = excluded
+	entry.deleting = 0
+	entry.failed = 0
+	entry.finished = time.Time{}
+}
+
+// Verified - a type was listed again after deleting. What is left is pending, the counts of the last deletion are kept
+func (t *Tracker) Verified(project, resourceType string, remaining int) {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	entry := t.get(project, resourceType)
+	entry.pending = remaining
+	entry.deleting = 0
+}
+
+// Deleting - the deletion of a pending item started
+func (t *Tracker) Deleting(project, resourceType string) {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	entry := t.get(project, resourceType)
+	entry.pending = max(entry.pending-1, 0)
+	entry.deleting++
+}
+
+// Finished - the deletion of an item ended, failed when err is set
+func (t *Tracker) Finished(project, resourceType string, err error) {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	entry := t.get(project, resourceType)
+	entry.deleting = max(entry.deleting-1, 0)
+	if err != nil {
+		entry.failed++
+		return
+	}
+	entry.deleted++
+}
+
+// Done - a type has nothing more running, its elapsed time stops
+func (t *Tracker) Done(project, resourceType string) {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.get(project, resourceType).finished = time.Now()
+}
+
+// Render - writes a header and one line per type, sorted by project and type, and returns the number of lines written
+func (t *Tracker) Render(w io.Writer) int {
+	if t == nil {
+		return 0
+	}
+	t.mutex.Lock()
+	entries := make([]counts, 0, len(t.types))
+	for _, entry := range t.types {
+		entries = append(entries, *entry)
+	}
+	start := t.start
+	t.mutex.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].project != entries[j].project {
+			return entries[i].project < entries[j].project
+		}
+		return entries[i].resourceType < entries[j].resourceType
+	})
+
+	now := time.Now()
+	writer := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(writer, "PROJECT\tTYPE\tPENDING\tDELETING\tDELETED\tEXCLUDED\tFAILED\tELAPSED %v\n", elapsed(start, now))
+	for _, entry := range entries {
+		end := entry.finished
+		if end.IsZero() {
+			end = now
+		}
+		fmt.Fprintf(writer, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n", entry.project, entry.resourceType, entry.pending, entry.deleting, entry.deleted, entry.excluded, entry.failed, elapsed(entry.started, end))
+	}
+	writer.Flush()
+	return len(entries) + 1
+}
+
+// elapsed - the time between start and end in whole seconds
+func elapsed(start, end time.Time) string {
+	return end.Sub(start).Round(time.Second).String()
+}
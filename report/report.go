@@ -0,0 +1,181 @@
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Actions recorded for a resource
+const (
+	Deleted     = "deleted"
+	WouldDelete = "would_delete"
+	Excluded    = "excluded"
+	Skipped     = "skipped"
+	Failed      = "failed"
+)
+
+// Formats supported by Write
+const (
+	FormatJSON     = "json"
+	FormatCSV      = "csv"
+	FormatMarkdown = "markdown"
+)
+
+// Entry - the outcome for a single resource
+type Entry struct {
+	Type     string  `json:"type"`
+	Name     string  `json:"name"`
+	Project  string  `json:"project"`
+	Location string  `json:"location,omitempty"`
+	Action   string  `json:"action"`
+	Reason   string  `json:"reason,omitempty"`
+	Duration float64 `json:"duration_seconds"`
+	Error    string  `json:"error,omitempty"`
+}
+
+func (e Entry) key() string {
+	return e.Project + "/" + e.Type + "/" + e.Location + "/" + e.Name
+}
+
+// Report - collects entries from every project and resource type of a run, safe for concurrent use
+type Report struct {
+	mutex   sync.Mutex
+	entries map[string]Entry
+}
+
+// New - creates an empty report
+func New() *Report {
+	return &Report{
+		entries: make(map[string]Entry),
+	}
+}
+
+// Add - records an entry, replacing any earlier entry for the same resource. A nil report discards entries
+func (r *Report) Add(entry Entry) {
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.entries[entry.key()] = entry
+}
+
+// AddIfAbsent - records an entry unless the resource already has one. A nil report discards entries
+func (r *Report) AddIfAbsent(entry Entry) {
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, found := r.entries[entry.key()]; !found {
+		r.entries[entry.key()] = entry
+	}
+}
+
+// Fail - records a failed entry, keeping the error and duration of an earlier entry for the same resource. A nil report discards entries
+func (r *Report) Fail(entry Entry) {
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	entry.Action = Failed
+	if earlier, found := r.entries[entry.key()]; found {
+		entry.Duration = earlier.Duration
+		entry.Error = earlier.Error
+	}
+	r.entries[entry.key()] = entry
+}
+
+// Record - records the outcome of a timed action, failed when err is set
+func (r *Report) Record(entry Entry, start time.Time, err error) {
+	entry.Duration = time.Since(start).Seconds()
+	if err != nil {
+		entry.Action = Failed
+		entry.Error = err.Error()
+	}
+	r.Add(entry)
+}
+
+// Entries - all entries sorted by project, type and name
+func (r *Report) Entries() []Entry {
+	if r == nil {
+		return nil
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	entries := make([]Entry, 0, len(r.entries))
+	for _, entry := range r.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Project != entries[j].Project {
+			return entries[i].Project < entries[j].Project
+		}
+		if entries[i].Type != entries[j].Type {
+			return entries[i].Type < entries[j].Type
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return entries
+}
+
+// WriteFile - writes the report in the given format to path, where "-" means stdout
+func (r *Report) WriteFile(format, path string) error {
+	if path == "-" {
+		return r.Write(format, os.Stdout)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return r.Write(format, file)
+}
+
+// Write - writes the report in the given format
+func (r *Report) Write(format string, w io.Writer) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(r.Entries())
+	case FormatCSV:
+		return r.writeCSV(w)
+	case FormatMarkdown:
+		return r.writeMarkdown(w)
+	}
+	return fmt.Errorf("unknown report format %q, expected %v, %v or %v", format, FormatJSON, FormatCSV, FormatMarkdown)
+}
+
+func (r *Report) writeCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"type", "name", "project", "location", "action", "reason", "duration_seconds", "error"})
+	for _, entry := range r.Entries() {
+		writer.Write([]string{entry.Type, entry.Name, entry.Project, entry.Location, entry.Action, entry.Reason, fmt.Sprintf("%.1f", entry.Duration), entry.Error})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func (r *Report) writeMarkdown(w io.Writer) error {
+	escape := strings.NewReplacer("|", "\\|", "\n", " ")
+	if _, err := fmt.Fprintln(w, "| Type | Name | Project | Location | Action | Reason | Duration (s) | Error |\n| --- | --- | --- | --- | --- | --- | --- | --- |"); err != nil {
+		return err
+	}
+	for _, entry := range r.Entries() {
+		_, err := fmt.Fprintf(w, "| %v | %v | %v | %v | %v | %v | %.1f | %v |\n",
+			entry.Type, escape.Replace(entry.Name), entry.Project, entry.Location, entry.Action, escape.Replace(entry.Reason), entry.Duration, escape.Replace(entry.Error))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
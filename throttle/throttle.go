@@ -0,0 +1,79 @@
+package throttle
+
+import (
+	"context"
+	"math"
+
+	"golang.org/x/time/rate"
+)
+
+// Limiter - bounds the API load of a run: deletions running at once, overall and per API, and API calls per second through a shared token bucket.
+// Shared by every project, safe for concurrent use. A nil Limiter does not limit
+type Limiter struct {
+	// all - one slot per running deletion, nil when unlimited
+	all chan struct{}
+	// apis - one slot per running deletion of an API, keyed by API name. APIs without an entry are only bound by all
+	apis map[string]chan struct{}
+	// calls - the token bucket, nil when unlimited
+	calls *rate.Limiter
+}
+
+// New - creates a limiter. A maxConcurrency, per API limit or callsPerSecond of 0 or less means unlimited
+func New(maxConcurrency int, apiConcurrency map[string]int, callsPerSecond float64) *Limiter {
+	limiter := &Limiter{
+		apis: make(map[string]chan struct{}),
+	}
+	if maxConcurrency > 0 {
+		limiter.all = make(chan struct{}, maxConcurrency)
+	}
+	for api, limit := range apiConcurrency {
+		if limit > 0 {
+			limiter.apis[api] = make(chan struct{}, limit)
+		}
+	}
+	if callsPerSecond > 0 {
+		// A burst of one second worth of calls lets an idle run start straight away
+		limiter.calls = rate.NewLimiter(rate.Limit(callsPerSecond), max(1, int(math.Ceil(callsPerSecond))))
+	}
+	return limiter
+}
+
+// Acquire - waits for a free deletion slot for the API and overall. The returned release frees both slots and must be called once the deletion has finished
+func (l *Limiter) Acquire(ctx context.Context, api string) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	// The API slot comes first, so deletions queued on a busy API do not hold overall slots other APIs could use
+	slots := []chan struct{}{}
+	if slot, limited := l.apis[api]; limited {
+		slots = append(slots, slot)
+	}
+	if l.all != nil {
+		slots = append(slots, l.all)
+	}
+
+	acquired := 0
+	release := func() {
+		for _, slot := range slots[:acquired] {
+			<-slot
+		}
+	}
+	for _, slot := range slots {
+		select {
+		case slot <- struct{}{}:
+			acquired++
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+	return release, nil
+}
+
+// Wait - waits until the token bucket allows another API call
+func (l *Limiter) Wait(ctx context.Context) error {
+	if l == nil || l.calls == nil {
+		return nil
+	}
+	return l.calls.Wait(ctx)
+}